@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"alert-webhook/utils"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// 投递结果
+const (
+	// OutcomeDelivered 接收者投递成功
+	OutcomeDelivered = "delivered"
+	// OutcomeRetrying 投递失败，保留在队列中等待重新投递
+	OutcomeRetrying = "retrying"
+	// OutcomeDeadLetter 投递永久失败，已写入死信
+	OutcomeDeadLetter = "dead_letter"
+	// OutcomeUnrouted 通过过滤规则但没有路由到任何接收者
+	OutcomeUnrouted = "unrouted"
+	// OutcomeUnconfigured 路由到的接收者没有可用的通知器，告警未写入投递队列
+	OutcomeUnconfigured = "unconfigured"
+)
+
+// Config 审计记录配置，通过过滤规则的每个告警按投递结果写入 syslog 和/或本地文件
+type Config struct {
+	Syslog SyslogConfig `yaml:"syslog"`
+	File   FileConfig   `yaml:"file"`
+}
+
+// Record 单个告警的一条审计记录，每个接收者的每次投递各一条
+type Record struct {
+	Time         time.Time         `json:"time"`
+	Receiver     string            `json:"receiver"`
+	Status       string            `json:"status"`
+	Fingerprint  string            `json:"fingerprint"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"starts_at"`
+	EndsAt       time.Time         `json:"ends_at"`
+	GeneratorURL string            `json:"generator_url,omitempty"`
+	Outcome      string            `json:"outcome"`
+	// 该告警在此接收者的第几次投递（队列层面，不含单次投递内的 HTTP 重试）
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// NewRecords 为一组告警生成审计记录，告警状态按自身状态计算
+func NewRecords(receiver string, data template.Data, outcome string, attempts int, err error) []Record {
+	now := time.Now()
+	records := make([]Record, 0, len(data.Alerts))
+	for _, alert := range data.Alerts {
+		status := alert.Status
+		if status == "" {
+			status = data.Status
+		}
+		record := Record{
+			Time:         now,
+			Receiver:     receiver,
+			Status:       status,
+			Fingerprint:  utils.AlertFingerprint(alert),
+			Labels:       alert.Labels,
+			Annotations:  alert.Annotations,
+			StartsAt:     alert.StartsAt,
+			EndsAt:       alert.EndsAt,
+			GeneratorURL: alert.GeneratorURL,
+			Outcome:      outcome,
+			Attempts:     attempts,
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Sink 审计记录的输出目标
+type Sink interface {
+	Write(record Record) error
+	Close() error
+}
+
+// Auditor 将审计记录写入全部已启用的输出目标，写入失败只记录日志，不影响告警投递
+type Auditor struct {
+	sinks []namedSink
+}
+
+type namedSink struct {
+	name string
+	sink Sink
+}
+
+// New 根据配置创建 Auditor，未启用任何输出目标时返回 nil，nil 的 Auditor 忽略全部记录
+func New(cfg Config) (*Auditor, error) {
+	var sinks []namedSink
+	if cfg.Syslog.Enabled {
+		sink, err := NewSyslogSink(cfg.Syslog)
+		if err != nil {
+			return nil, fmt.Errorf("syslog 审计配置错误: %w", err)
+		}
+		sinks = append(sinks, namedSink{name: "syslog", sink: sink})
+	}
+	if cfg.File.Enabled {
+		sink, err := NewFileSink(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("文件审计配置错误: %w", err)
+		}
+		sinks = append(sinks, namedSink{name: "file", sink: sink})
+	}
+
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return &Auditor{sinks: sinks}, nil
+}
+
+// Record 写入审计记录
+func (a *Auditor) Record(records ...Record) {
+	if a == nil {
+		return
+	}
+	for _, record := range records {
+		for _, s := range a.sinks {
+			if err := s.sink.Write(record); err != nil {
+				log.Printf("[audit] 写入 %s 审计记录失败: %v", s.name, err)
+			}
+		}
+	}
+}
+
+// Close 关闭全部输出目标
+func (a *Auditor) Close() {
+	if a == nil {
+		return
+	}
+	for _, s := range a.sinks {
+		if err := s.sink.Close(); err != nil {
+			log.Printf("[audit] 关闭 %s 审计输出失败: %v", s.name, err)
+		}
+	}
+}
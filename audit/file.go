@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/natefinch/lumberjack"
+)
+
+// FileConfig 本地 JSON Lines 审计文件配置，按大小轮转
+type FileConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// 文件路径，默认 ./logs/alerts-audit.jsonl
+	Path string `yaml:"path"`
+	// 单个文件的最大大小（MB），默认 100
+	MaxSize int `yaml:"max_size"`
+	// 保留的历史文件数量，0 表示全部保留
+	MaxBackups int `yaml:"max_backups"`
+	// 历史文件保留天数，0 表示不按时间删除
+	MaxAge int `yaml:"max_age"`
+	// 是否 gzip 压缩历史文件
+	Compress bool `yaml:"compress"`
+}
+
+// FileSink 每条审计记录写为一行 JSON，文件轮转使用 lumberjack
+type FileSink struct {
+	mu     sync.Mutex
+	writer *lumberjack.Logger
+}
+
+// NewFileSink 创建文件审计输出，文件在首次写入时创建
+func NewFileSink(cfg FileConfig) (*FileSink, error) {
+	if cfg.MaxSize < 0 || cfg.MaxBackups < 0 || cfg.MaxAge < 0 {
+		return nil, fmt.Errorf("max_size、max_backups、max_age 不能为负数")
+	}
+	path := cfg.Path
+	if path == "" {
+		path = "./logs/alerts-audit.jsonl"
+	}
+	maxSize := cfg.MaxSize
+	if maxSize == 0 {
+		maxSize = 100
+	}
+
+	return &FileSink{writer: &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSize,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAge,
+		Compress:   cfg.Compress,
+	}}, nil
+}
+
+// Write 写入一行 JSON，一条记录只调用一次 Write，轮转时不会拆分记录
+func (f *FileSink) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.writer.Write(line)
+	return err
+}
+
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writer.Close()
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSinkJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileSink(FileConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := testRecord(), testRecord()
+	second.Outcome = OutcomeDeadLetter
+	second.Error = "permanent failure"
+	for _, record := range []Record{first, second} {
+		if err := sink.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 || records[0].Outcome != OutcomeDelivered || records[1].Outcome != OutcomeDeadLetter || records[1].Error != "permanent failure" {
+		t.Errorf("records = %+v", records)
+	}
+}
+
+func TestFileSinkRotatesAndPrunesBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	sink, err := NewFileSink(FileConfig{Path: path, MaxSize: 1, MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// 每条记录约 300KB，max_size 1MB 下每 3 条轮转一次
+	record := testRecord()
+	record.Annotations = map[string]string{"description": strings.Repeat("x", 300*1024)}
+	for i := 0; i < 12; i++ {
+		if err := sink.Write(record); err != nil {
+			t.Fatal(err)
+		}
+		// 历史文件名精确到毫秒，避免同一毫秒内的两次轮转使用相同文件名
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// lumberjack 在后台协程中删除多余的历史文件
+	var backups []string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		backups, err = filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backups) <= 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want max_backups 1", backups)
+	}
+
+	for _, name := range append(backups, path) {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 1024*1024 {
+			t.Errorf("%s size = %d, want at most 1MB", name, info.Size())
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 轮转不拆分记录，每个文件都以完整的一行结尾
+		for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			if !json.Valid([]byte(line)) {
+				t.Errorf("%s contains a partial record", name)
+			}
+		}
+	}
+}
+
+func TestFileSinkRejectsNegativeLimits(t *testing.T) {
+	if _, err := NewFileSink(FileConfig{MaxBackups: -1}); err == nil {
+		t.Error("expected error for negative max_backups")
+	}
+}
+
+func TestNewWithoutSinksReturnsNil(t *testing.T) {
+	auditor, err := New(Config{})
+	if err != nil || auditor != nil {
+		t.Fatalf("New = %v, %v, want nil auditor", auditor, err)
+	}
+	// nil 的 Auditor 忽略全部记录
+	auditor.Record(testRecord())
+	auditor.Close()
+}
@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogDialTimeout 连接 syslog 服务的超时时间
+const syslogDialTimeout = 5 * time.Second
+
+// syslogReconnectInterval 连接失败后在此时间内不再重连，直接丢弃记录，避免 syslog 服务不可用时拖慢告警投递
+const syslogReconnectInterval = 30 * time.Second
+
+// syslogSDID 结构化数据的 SD-ID，32473 为 RFC 5612 中用于示例的企业编号
+const syslogSDID = "alert@32473"
+
+// syslogFacilities facility 名称 -> 编号
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslog severity
+const (
+	syslogAlert   = 1
+	syslogCrit    = 2
+	syslogWarning = 4
+	syslogNotice  = 5
+	syslogInfo    = 6
+)
+
+// SyslogConfig syslog 审计配置，消息格式为 RFC 5424
+type SyslogConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// 传输协议：udp（默认）、tcp、unix
+	Network string `yaml:"network"`
+	// 服务地址：udp/tcp 为 host:port，默认 127.0.0.1:514；unix 为 socket 路径，默认 /dev/log
+	Address string `yaml:"address"`
+	// facility 名称，默认 local0
+	Facility string `yaml:"facility"`
+	// APP-NAME，默认 alert-webhook
+	AppName string `yaml:"app_name"`
+	// HOSTNAME，默认为本机主机名
+	Hostname string `yaml:"hostname"`
+}
+
+// SyslogSink 将审计记录按 RFC 5424 格式发送到 syslog，首次写入时连接，写入失败时重新连接一次
+type SyslogSink struct {
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+	procID   string
+
+	mu   sync.Mutex
+	conn net.Conn
+	// 实际使用的传输协议，unix 优先使用 unixgram
+	connNetwork string
+	// 上次连接失败后允许重连的时间
+	reconnectAt time.Time
+}
+
+// NewSyslogSink 校验配置并创建 syslog 审计输出
+func NewSyslogSink(cfg SyslogConfig) (*SyslogSink, error) {
+	network := strings.ToLower(cfg.Network)
+	address := cfg.Address
+	switch network {
+	case "", "udp", "tcp":
+		if network == "" {
+			network = "udp"
+		}
+		if address == "" {
+			address = "127.0.0.1:514"
+		}
+	case "unix":
+		if address == "" {
+			address = "/dev/log"
+		}
+	default:
+		return nil, fmt.Errorf("network %s 不受支持，可选: udp、tcp、unix", cfg.Network)
+	}
+
+	facilityName := cfg.Facility
+	if facilityName == "" {
+		facilityName = "local0"
+	}
+	facility, ok := syslogFacilities[strings.ToLower(facilityName)]
+	if !ok {
+		return nil, fmt.Errorf("facility %s 不受支持", cfg.Facility)
+	}
+
+	appName := cfg.AppName
+	if appName == "" {
+		appName = "alert-webhook"
+	}
+	hostname := cfg.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	return &SyslogSink{
+		network:  network,
+		address:  address,
+		facility: facility,
+		appName:  syslogHeaderField(appName, 48),
+		hostname: syslogHeaderField(hostname, 255),
+		procID:   strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+// Write 发送一条 syslog 消息，连接断开时重新连接后再发送一次
+func (s *SyslogSink) Write(record Record) error {
+	msg, err := s.format(record, time.Now())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.send(msg); err != nil {
+		s.closeConn()
+		return s.send(msg)
+	}
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeConn()
+}
+
+// send 按传输协议分帧发送：udp/unixgram 一个数据报一条消息，tcp 使用 RFC 6587 的长度前缀，unix 流式 socket 以换行结尾
+func (s *SyslogSink) send(msg string) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(syslogDialTimeout)); err != nil {
+		return err
+	}
+	switch s.connNetwork {
+	case "tcp":
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	case "unix":
+		msg += "\n"
+	}
+	_, err := s.conn.Write([]byte(msg))
+	return err
+}
+
+// connect 连接 syslog 服务，unix 与 log/syslog 相同，先尝试 unixgram 再尝试流式 socket
+func (s *SyslogSink) connect() error {
+	if time.Now().Before(s.reconnectAt) {
+		return fmt.Errorf("syslog 服务 %s 不可用，%s 后重新连接", s.address, s.reconnectAt.Format(time.TimeOnly))
+	}
+
+	networks := []string{s.network}
+	if s.network == "unix" {
+		networks = []string{"unixgram", "unix"}
+	}
+
+	var err error
+	for _, network := range networks {
+		var conn net.Conn
+		conn, err = net.DialTimeout(network, s.address, syslogDialTimeout)
+		if err == nil {
+			s.conn = conn
+			s.connNetwork = network
+			return nil
+		}
+	}
+	s.reconnectAt = time.Now().Add(syslogReconnectInterval)
+	return fmt.Errorf("连接 syslog 服务 %s 失败: %w", s.address, err)
+}
+
+func (s *SyslogSink) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// format 生成 RFC 5424 消息：<PRI>1 时间戳 主机名 APP-NAME PROCID MSGID [结构化数据] JSON 记录
+func (s *SyslogSink) format(record Record, now time.Time) (string, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	pri := s.facility*8 + syslogSeverity(record)
+	sd := fmt.Sprintf(`[%s receiver="%s" outcome="%s" status="%s" fingerprint="%s" alertname="%s" severity="%s"]`,
+		syslogSDID,
+		syslogParamValue(record.Receiver),
+		syslogParamValue(record.Outcome),
+		syslogParamValue(record.Status),
+		syslogParamValue(record.Fingerprint),
+		syslogParamValue(record.Labels["alertname"]),
+		syslogParamValue(record.Labels["severity"]))
+
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		pri, now.Format("2006-01-02T15:04:05.000000Z07:00"), s.hostname, s.appName, s.procID, record.Outcome, sd, body), nil
+}
+
+// syslogSeverity 告警中的告警按告警级别映射 syslog severity，已恢复的告警为 notice
+func syslogSeverity(record Record) int {
+	if record.Status == "resolved" {
+		return syslogNotice
+	}
+	switch record.Labels["severity"] {
+	case "emergency":
+		return syslogAlert
+	case "critical":
+		return syslogCrit
+	case "warning":
+		return syslogWarning
+	case "info":
+		return syslogInfo
+	default:
+		return syslogNotice
+	}
+}
+
+// syslogParamValue 转义结构化数据参数值中的 "、\ 和 ]
+func syslogParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// syslogHeaderField 头部字段只能包含可打印 ASCII 字符且不能含空格，为空时使用 -
+func syslogHeaderField(value string, maxLength int) string {
+	var builder strings.Builder
+	for _, r := range value {
+		if r > 32 && r < 127 && builder.Len() < maxLength {
+			builder.WriteRune(r)
+		}
+	}
+	if builder.Len() == 0 {
+		return "-"
+	}
+	return builder.String()
+}
@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRecord() Record {
+	return Record{
+		Receiver:    "ops",
+		Status:      "firing",
+		Fingerprint: "0123456789abcdef",
+		Labels:      map[string]string{"alertname": "High\"CPU]", "severity": "critical"},
+		Outcome:     OutcomeDelivered,
+		Attempts:    1,
+	}
+}
+
+func TestSyslogFormat(t *testing.T) {
+	sink, err := NewSyslogSink(SyslogConfig{Facility: "local0", AppName: "alert webhook", Hostname: "node 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := testRecord()
+	now := time.Date(2026, 10, 1, 8, 0, 0, 123456000, time.UTC)
+
+	msg, err := sink.format(record, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local0(16)*8 + critical(2) = 130，头部字段中的空格被去掉，结构化数据中的 " 和 ] 被转义
+	want := "<130>1 2026-10-01T08:00:00.123456Z node1 alertwebhook " + strconv.Itoa(os.Getpid()) + " delivered " +
+		`[alert@32473 receiver="ops" outcome="delivered" status="firing" fingerprint="0123456789abcdef" alertname="High\"CPU\]" severity="critical"] `
+	if !strings.HasPrefix(msg, want) {
+		t.Fatalf("message = %q\nwant prefix %q", msg, want)
+	}
+	var body Record
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(msg, want)), &body); err != nil {
+		t.Fatalf("message body is not JSON: %v", err)
+	}
+	if body.Receiver != "ops" || body.Labels["alertname"] != record.Labels["alertname"] {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		status   string
+		severity string
+		want     int
+	}{
+		{"firing", "emergency", syslogAlert},
+		{"firing", "critical", syslogCrit},
+		{"firing", "warning", syslogWarning},
+		{"firing", "info", syslogInfo},
+		{"firing", "", syslogNotice},
+		{"resolved", "critical", syslogNotice},
+	}
+	for _, tt := range tests {
+		record := Record{Status: tt.status, Labels: map[string]string{"severity": tt.severity}}
+		if got := syslogSeverity(record); got != tt.want {
+			t.Errorf("syslogSeverity(%s, %s) = %d, want %d", tt.status, tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogHeaderField(t *testing.T) {
+	if got := syslogHeaderField("  ", 48); got != "-" {
+		t.Errorf("empty field = %q, want -", got)
+	}
+	if got := syslogHeaderField("主机a b", 48); got != "ab" {
+		t.Errorf("non-ASCII field = %q, want ab", got)
+	}
+	if got := syslogHeaderField(strings.Repeat("x", 60), 48); len(got) != 48 {
+		t.Errorf("field length = %d, want 48", len(got))
+	}
+}
+
+func TestSyslogTCPOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	sink, err := NewSyslogSink(SyslogConfig{Network: "tcp", Address: listener.Addr().String(), Hostname: "node1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	first, second := testRecord(), testRecord()
+	second.Outcome = OutcomeRetrying
+	for _, record := range []Record{first, second} {
+		if err := sink.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	// RFC 6587 octet counting：MSG-LEN SP SYSLOG-MSG，消息之间没有分隔符
+	for _, outcome := range []string{OutcomeDelivered, OutcomeRetrying} {
+		length, err := reader.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+		if err != nil {
+			t.Fatalf("frame length %q: %v", length, err)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(reader, msg); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(msg), "<130>1 ") || !strings.Contains(string(msg), " "+outcome+" [alert@32473 ") || !strings.HasSuffix(string(msg), "}") {
+			t.Errorf("frame = %q", msg)
+		}
+	}
+}
+
+func TestSyslogUDPDatagram(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink(SyslogConfig{Address: conn.LocalAddr().String(), Facility: "local7", Hostname: "node1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	record := testRecord()
+	record.Status = "resolved"
+	if err := sink.Write(record); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64*1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 一个数据报一条消息，没有长度前缀；local7(23)*8 + notice(5) = 189
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<189>1 ") || !strings.HasSuffix(msg, "}") {
+		t.Errorf("datagram = %q", msg)
+	}
+}
+
+func TestSyslogConfigErrors(t *testing.T) {
+	if _, err := NewSyslogSink(SyslogConfig{Network: "http"}); err == nil {
+		t.Error("expected error for unsupported network")
+	}
+	if _, err := NewSyslogSink(SyslogConfig{Facility: "local9"}); err == nil {
+		t.Error("expected error for unknown facility")
+	}
+}
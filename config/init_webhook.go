@@ -2,25 +2,22 @@ package config
 
 import (
 	"alert-webhook/console"
-	"bytes"
-	"encoding/json"
+	"alert-webhook/notifier"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
-	"time"
 )
 
 var (
 	EnabledClients []string
-	Notifiers      map[string]string // client -> webhook_url
+	Notifiers      map[string]notifier.Notifier // 接收者名称 -> 通知器
 	ServerPort     string
 	GlobalConfig   *AppConfig // 全局配置引用
 )
 
-func init() {
+// Init 解析命令行参数、加载配置文件并创建全部通知器，由 main 在启动服务前调用
+func Init() {
 	// 加载配置
 	configPath := flag.String("config", "./config.yaml", "配置文件路径")
 	flag.Parse()
@@ -30,16 +27,21 @@ func init() {
 		defaultConfig := `server:
   port: "0.0.0.0:18082"
 
-# 消息接收客户端，支持配置数组同时发送
+# 启用的接收者名称，支持配置数组同时发送
 client:
   - wechat
 
+# 接收者配置：名称 -> 类型(wechat/dingtalk/feishu) + webhook_url
+# 未配置 type 时以名称作为类型
 notifiers:
   wechat:
+    type: wechat
     webhook_url: "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=xxxxxxxxxxxxxxxxxxxxxx"
   dingtalk:
+    type: dingtalk
     webhook_url: "https://oapi.dingtalk.com/robot/send?access_token=xxxxxxxxxxxxxxxxxxxxxxxxx"
   feishu:
+    type: feishu
     webhook_url: "https://open.feishu.cn/open-apis/bot/v2/hook/xxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
 
 clickhouse:
@@ -114,104 +116,34 @@ filter:
 	ServerPort = cfg.Server.Port
 
 	// 构建通知器映射
-	Notifiers = make(map[string]string)
-	for client, config := range cfg.Notifiers {
-		Notifiers[client] = config.WebhookURL
+	Notifiers = make(map[string]notifier.Notifier)
+	for name, receiver := range cfg.Notifiers {
+		n, err := notifier.New(name, receiver)
+		if err != nil {
+			log.Fatalf("接收者 %s 初始化失败: %v", name, err)
+		}
+		Notifiers[name] = n
 	}
 }
 
-// TestClientsConnection 测试所有启用的客户端连通性
+// TestClientsConnection 测试所有启用的客户端及路由引用的接收者的连通性
 func TestClientsConnection() bool {
 	allSuccess := true
 
-	for _, client := range EnabledClients {
-		url, ok := Notifiers[client]
+	for _, client := range GlobalConfig.ActiveReceivers() {
+		n, ok := Notifiers[client]
 		if !ok {
 			log.Printf("客户端 %s 未配置", client)
 			continue
 		}
 
-		var msg map[string]interface{}
-
-		switch client {
-		case "wechat":
-			msg = map[string]interface{}{
-				"msgtype": "markdown",
-				"markdown": map[string]string{
-					"content": "[测试连接]企业微信",
-				},
-			}
-		case "dingtalk":
-			msg = map[string]interface{}{
-				"msgtype": "markdown",
-				"markdown": map[string]string{
-					"title": "钉钉连通性测试",
-					"text":  "钉钉连通性测试",
-				},
-			}
-		case "feishu":
-			msg = map[string]interface{}{
-				"msg_type": "text",
-				"content": map[string]string{
-					"text": "飞书连通性测试",
-				},
-			}
-		default:
-			log.Printf("未知客户端类型: %s", client)
-			continue
-		}
-
-		if success := sendTestMessage(client, url, msg); success {
-			log.Printf("[Connection Test Success] %s 连通性测试成功", client)
-		} else {
-			log.Printf("[Connection Test Faield] %s 连通性测试失败", client)
+		if err := n.TestConnection(); err != nil {
+			log.Printf("[Connection Test Faield] %s 连通性测试失败: %v", client, err)
 			allSuccess = false
+		} else {
+			log.Printf("[Connection Test Success] %s 连通性测试成功", client)
 		}
 	}
 
 	return allSuccess
 }
-
-// sendTestMessage 发送测试连接消息
-func sendTestMessage(clientType, url string, msg map[string]interface{}) bool {
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("[%s] JSON 编码失败: %v", clientType, err)
-		return false
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("[%s] 创建请求失败: %v", clientType, err)
-		return false
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	// 修复：将变量名改为 httpClient 避免冲突
-	httpClient := &http.Client{Timeout: 5 * time.Second}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Printf("[%s] 发送请求失败: %v", clientType, err)
-		return false
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Fatalln("close body err:", err)
-		}
-	}(resp.Body)
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("[%s] 读取响应失败: %v", clientType, err)
-		return false
-	}
-
-	// 检查响应状态
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		log.Printf("[%s] 返回错误状态码: %d, 响应: %s", clientType, resp.StatusCode, string(body))
-		return false
-	}
-
-	return true
-}
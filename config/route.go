@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// Route 告警路由节点，语义与 Alertmanager 的路由树一致
+// 告警从根节点开始向下匹配，子路由按顺序匹配，命中第一个子路由后停止，
+// 除非该子路由配置了 continue；没有子路由命中时使用当前节点的接收者
+type Route struct {
+	// 接收者名称，为空时继承父路由的接收者
+	Receiver string `yaml:"receiver"`
+	// 标签匹配规则，支持 =、!=、=~、!~，例如 team="dba"、severity=~"critical|emergency"
+	Matchers []string `yaml:"matchers"`
+	// 命中后是否继续匹配后续兄弟路由
+	Continue bool `yaml:"continue"`
+	// 子路由
+	Routes []*Route `yaml:"routes"`
+
+	matchers []*labels.Matcher
+}
+
+// compile 解析匹配规则并校验接收者是否存在
+func (r *Route) compile(receivers map[string]NotifierConfig) error {
+	if r.Receiver != "" {
+		if _, ok := receivers[r.Receiver]; !ok {
+			return fmt.Errorf("路由引用的接收者 %s 未配置", r.Receiver)
+		}
+	}
+
+	r.matchers = make([]*labels.Matcher, 0, len(r.Matchers))
+	for _, s := range r.Matchers {
+		m, err := labels.ParseMatcher(s)
+		if err != nil {
+			return fmt.Errorf("路由匹配规则 %q 解析失败: %w", s, err)
+		}
+		r.matchers = append(r.matchers, m)
+	}
+
+	for _, child := range r.Routes {
+		if err := child.compile(receivers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches 判断告警标签是否满足当前路由的所有匹配规则，未配置的标签按空字符串处理
+func (r *Route) matches(alertLabels template.KV) bool {
+	for _, m := range r.matchers {
+		if !m.Matches(alertLabels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match 返回告警应发送的接收者名称，defaults 为根路由未配置接收者时使用的接收者
+func (r *Route) Match(alertLabels template.KV, defaults []string) []string {
+	var receivers []string
+	seen := make(map[string]bool)
+	for _, name := range r.match(alertLabels, defaults) {
+		if !seen[name] {
+			seen[name] = true
+			receivers = append(receivers, name)
+		}
+	}
+	return receivers
+}
+
+func (r *Route) match(alertLabels template.KV, inherited []string) []string {
+	if !r.matches(alertLabels) {
+		return nil
+	}
+
+	current := inherited
+	if r.Receiver != "" {
+		current = []string{r.Receiver}
+	}
+
+	var receivers []string
+	for _, child := range r.Routes {
+		matched := child.match(alertLabels, current)
+		if len(matched) == 0 {
+			continue
+		}
+		receivers = append(receivers, matched...)
+		if !child.Continue {
+			break
+		}
+	}
+
+	if len(receivers) == 0 {
+		return current
+	}
+	return receivers
+}
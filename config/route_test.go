@@ -0,0 +1,156 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+	"gopkg.in/yaml.v2"
+)
+
+// testReceivers 路由测试中可引用的接收者
+var testReceivers = map[string]NotifierConfig{
+	"default": {}, "dba": {}, "dba-oncall": {}, "sre": {}, "audit": {}, "p0-sms": {},
+}
+
+// parseRoute 解析并编译 YAML 格式的路由树
+func parseRoute(t *testing.T, src string) *Route {
+	t.Helper()
+	var route Route
+	if err := yaml.Unmarshal([]byte(src), &route); err != nil {
+		t.Fatalf("parse route: %v", err)
+	}
+	if err := route.compile(testReceivers); err != nil {
+		t.Fatalf("compile route: %v", err)
+	}
+	return &route
+}
+
+const testRouteTree = `
+receiver: default
+routes:
+  - receiver: audit
+    continue: true
+  - receiver: dba
+    matchers: ['team="dba"']
+    routes:
+      - receiver: dba-oncall
+        matchers: ['severity=~"critical|emergency"']
+  - receiver: sre
+    matchers: ['team=~"sre|infra"', 'env!="test"']
+  - matchers: ['severity="emergency"']
+    routes:
+      - receiver: p0-sms
+        matchers: ['team="sre"']
+`
+
+func TestRouteMatch(t *testing.T) {
+	route := parseRoute(t, testRouteTree)
+
+	tests := []struct {
+		name   string
+		labels template.KV
+		want   []string
+	}{
+		{
+			name:   "continue fans out to the next matching sibling",
+			labels: template.KV{"team": "dba", "severity": "warning"},
+			want:   []string{"audit", "dba"},
+		},
+		{
+			name:   "deepest matching child wins",
+			labels: template.KV{"team": "dba", "severity": "critical"},
+			want:   []string{"audit", "dba-oncall"},
+		},
+		{
+			name:   "first match without continue stops later siblings",
+			labels: template.KV{"team": "sre", "severity": "emergency", "env": "prod"},
+			want:   []string{"audit", "sre"},
+		},
+		{
+			name:   "negative matcher excludes the route",
+			labels: template.KV{"team": "infra", "env": "test"},
+			want:   []string{"audit"},
+		},
+		{
+			name:   "child without receiver inherits parent receiver",
+			labels: template.KV{"team": "web", "severity": "emergency"},
+			want:   []string{"audit", "default"},
+		},
+		{
+			name:   "missing label matches as empty string",
+			labels: template.KV{"severity": "emergency"},
+			want:   []string{"audit", "default"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := route.Match(tt.labels, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match(%v) = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteWithoutContinueFallsBackToParent(t *testing.T) {
+	route := parseRoute(t, `
+receiver: default
+routes:
+  - receiver: dba
+    matchers: ['team="dba"']
+`)
+	if got := route.Match(template.KV{"team": "web"}, nil); !reflect.DeepEqual(got, []string{"default"}) {
+		t.Errorf("Match = %v, want [default]", got)
+	}
+}
+
+func TestRouteRootWithoutReceiverUsesDefaults(t *testing.T) {
+	route := parseRoute(t, `
+routes:
+  - receiver: dba
+    matchers: ['team="dba"']
+`)
+	defaults := []string{"default", "sre"}
+	if got := route.Match(template.KV{"team": "web"}, defaults); !reflect.DeepEqual(got, defaults) {
+		t.Errorf("Match = %v, want %v", got, defaults)
+	}
+	if got := route.Match(template.KV{"team": "dba"}, defaults); !reflect.DeepEqual(got, []string{"dba"}) {
+		t.Errorf("Match = %v, want [dba]", got)
+	}
+}
+
+func TestRouteMatchDeduplicatesReceivers(t *testing.T) {
+	route := parseRoute(t, `
+receiver: default
+routes:
+  - receiver: dba
+    continue: true
+  - receiver: dba
+    matchers: ['team="dba"']
+`)
+	if got := route.Match(template.KV{"team": "dba"}, nil); !reflect.DeepEqual(got, []string{"dba"}) {
+		t.Errorf("Match = %v, want [dba]", got)
+	}
+}
+
+func TestRouteCompileErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "receiver: unknown", want: "unknown"},
+		{src: "routes:\n  - receiver: missing\n    matchers: ['team=\"dba\"']", want: "missing"},
+		{src: "receiver: default\nmatchers: ['team=~\"(\"']", want: "解析失败"},
+	}
+	for _, tt := range tests {
+		var route Route
+		if err := yaml.Unmarshal([]byte(tt.src), &route); err != nil {
+			t.Fatalf("parse route: %v", err)
+		}
+		err := route.compile(testReceivers)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("compile(%q) = %v, want error containing %q", tt.src, err, tt.want)
+		}
+	}
+}
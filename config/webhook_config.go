@@ -1,10 +1,16 @@
 package config
 
 import (
+	"alert-webhook/audit"
+	"alert-webhook/notifier"
+	"alert-webhook/utils"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	texttemplate "text/template"
 
+	"github.com/prometheus/alertmanager/template"
 	"gopkg.in/yaml.v2"
 )
 
@@ -57,24 +63,82 @@ type SeverityFilter struct {
 	Exclude []string `yaml:"exclude"`
 }
 
-type NotifierConfig struct {
-	WebhookURL string `yaml:"webhook_url"`
+// QueueConfig 持久化投递队列配置
+type QueueConfig struct {
+	// 队列数据目录，保存 WAL 和死信文件
+	DataDir string `yaml:"data_dir"`
+	// 投递失败后重新投递的间隔（秒）
+	RetryInterval int `yaml:"retry_interval"`
+	// 最大投递次数，超过后写入死信
+	MaxAttempts int `yaml:"max_attempts"`
+	// 队列中未完成投递的上限，超过后接口返回 503
+	MaxPending int `yaml:"max_pending"`
+	// 同时投递的协程数上限
+	Workers int `yaml:"workers"`
 }
 
+// NotifierConfig 通知器配置，具体字段由 notifier 包定义
+type NotifierConfig = notifier.Config
+
 type ServerConfig struct {
 	Port string `yaml:"port"`
+	// 异步接收模式：校验并写入投递队列后立即返回 202，不等待发送结果
+	Async bool `yaml:"async"`
+	// 同步模式下等待首次投递结果的最长时间（秒），超时后返回 202，未完成的投递由队列继续完成
+	SyncTimeout int `yaml:"sync_timeout"`
 }
 
 type AppConfig struct {
-	Clients      []string                  `yaml:"client"`
-	Notifiers    map[string]NotifierConfig `yaml:"notifiers"`
-	Server       ServerConfig              `yaml:"server"`
+	Clients   []string                  `yaml:"client"`
+	Notifiers map[string]NotifierConfig `yaml:"notifiers"`
+	Server    ServerConfig              `yaml:"server"`
 	// 告警过滤规则
-	Filter       AlertFilter               `yaml:"filter"`
+	Filter AlertFilter `yaml:"filter"`
 	// ClickHouse配置
-	ClickHouse   ClickHouseConfig          `yaml:"clickhouse"`
+	ClickHouse ClickHouseConfig `yaml:"clickhouse"`
 	// 大流量告警配置
-	TrafficAlert TrafficAlertConfig        `yaml:"traffic_alert"`
+	TrafficAlert TrafficAlertConfig `yaml:"traffic_alert"`
+	// 告警路由树，未配置时所有告警发送到 client 中的全部接收者
+	Route *Route `yaml:"route"`
+	// 全局发送超时与重试配置，接收者可单独覆盖
+	Delivery notifier.DeliveryConfig `yaml:"delivery"`
+	// 持久化投递队列配置
+	Queue QueueConfig `yaml:"queue"`
+	// 审计记录配置，通过过滤规则的告警逐条写入 syslog 或本地文件
+	Audit audit.Config `yaml:"audit"`
+	// 自定义消息模板，名称 -> 模板文件路径，相对路径相对于配置文件所在目录
+	Templates map[string]string `yaml:"templates"`
+	// 消息中时间的时区（IANA 名称）和 Go 时间格式，接收者可单独覆盖
+	Timezone   string `yaml:"timezone"`
+	TimeFormat string `yaml:"time_format"`
+}
+
+// receiverTemplate 根据接收者配置的模板名称查找已加载的模板，未配置时返回 nil
+func receiverTemplate(name string, cfg notifier.TemplateConfig, templates utils.MessageTemplates, tf utils.TimeFormat) (*utils.MessageTemplate, error) {
+	if cfg.Firing == "" && cfg.Resolved == "" {
+		return nil, nil
+	}
+
+	lookup := func(status, templateName string) (*texttemplate.Template, error) {
+		if templateName == "" {
+			return nil, nil
+		}
+		tmpl, ok := templates[templateName]
+		if !ok {
+			return nil, fmt.Errorf("接收者 %s 的 %s 模板 %s 未在 templates 中定义", name, status, templateName)
+		}
+		return tmpl, nil
+	}
+
+	firing, err := lookup("firing", cfg.Firing)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := lookup("resolved", cfg.Resolved)
+	if err != nil {
+		return nil, err
+	}
+	return utils.NewMessageTemplate(firing, resolved, tf)
 }
 
 // LoadConfig 根据传入配置文件的路径 --- 加载配置
@@ -92,22 +156,133 @@ func LoadConfig(path string) (*AppConfig, error) {
 	}
 
 	// 验证配置
-	if len(config.Clients) == 0 {
-		return nil, fmt.Errorf("未配置任何客户端")
+	if len(config.Clients) == 0 && config.Route == nil {
+		return nil, fmt.Errorf("未配置任何客户端或路由")
+	}
+
+	if config.Server.SyncTimeout <= 0 {
+		config.Server.SyncTimeout = 30
+	}
+	if config.Queue.DataDir == "" {
+		config.Queue.DataDir = "./data"
+	}
+	if config.Queue.RetryInterval <= 0 {
+		config.Queue.RetryInterval = 60
+	}
+	if config.Queue.MaxAttempts <= 0 {
+		config.Queue.MaxAttempts = 10
+	}
+	if config.Queue.MaxPending <= 0 {
+		config.Queue.MaxPending = 10000
+	}
+	if config.Queue.Workers <= 0 {
+		config.Queue.Workers = 4
+	}
+
+	config.Delivery = config.Delivery.Merge(notifier.DefaultDeliveryConfig())
+	if *config.Delivery.MaxRetries < 0 {
+		return nil, fmt.Errorf("delivery.max_retries 不能为负数")
+	}
+
+	if config.Timezone == "" {
+		config.Timezone = utils.DefaultTimezone
+	}
+	if config.TimeFormat == "" {
+		config.TimeFormat = utils.DefaultTimeLayout
+	}
+	if _, err := utils.NewTimeFormat(config.Timezone, config.TimeFormat); err != nil {
+		return nil, err
+	}
+
+	templates, err := utils.LoadMessageTemplates(config.Templates, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	// 接收者未声明 type 时，沿用旧配置方式以名称作为类型
+	for name, receiver := range config.Notifiers {
+		if receiver.Type == "" {
+			receiver.Type = name
+		}
+		receiver.Delivery = receiver.Delivery.Merge(config.Delivery)
+		if *receiver.Delivery.MaxRetries < 0 {
+			return nil, fmt.Errorf("接收者 %s 的 delivery.max_retries 不能为负数", name)
+		}
+		if !notifier.IsRegistered(receiver.Type) {
+			return nil, fmt.Errorf("接收者 %s 的类型 %s 不受支持，可选类型: %v", name, receiver.Type, notifier.Types())
+		}
+		if receiver.Timezone == "" {
+			receiver.Timezone = config.Timezone
+		}
+		if receiver.TimeFormat == "" {
+			receiver.TimeFormat = config.TimeFormat
+		}
+		receiver.Time, err = utils.NewTimeFormat(receiver.Timezone, receiver.TimeFormat)
+		if err != nil {
+			return nil, fmt.Errorf("接收者 %s 的时间配置错误: %w", name, err)
+		}
+		receiver.MessageTemplate, err = receiverTemplate(name, receiver.Template, templates, receiver.Time)
+		if err != nil {
+			return nil, err
+		}
+		config.Notifiers[name] = receiver
 	}
 
 	for _, client := range config.Clients {
 		if _, ok := config.Notifiers[client]; !ok {
 			return nil, fmt.Errorf("客户端 %s 的配置缺失", client)
 		}
-		if config.Notifiers[client].WebhookURL == "" {
-			return nil, fmt.Errorf("客户端 %s 的Webhook URL未配置", client)
+	}
+
+	if config.Route != nil {
+		if err := config.Route.compile(config.Notifiers); err != nil {
+			return nil, err
+		}
+		if config.Route.Receiver == "" && len(config.Clients) == 0 {
+			return nil, fmt.Errorf("根路由未配置默认接收者，且未配置任何客户端")
 		}
 	}
 
 	return config, nil
 }
 
+// MatchReceivers 根据路由树返回告警应发送的接收者名称
+// 未配置路由时返回 client 中的全部接收者
+func (c *AppConfig) MatchReceivers(alert template.Alert) []string {
+	if c.Route == nil {
+		return c.Clients
+	}
+	return c.Route.Match(alert.Labels, c.Clients)
+}
+
+// ActiveReceivers 返回 client 和路由树中引用到的全部接收者名称
+func (c *AppConfig) ActiveReceivers() []string {
+	seen := make(map[string]bool)
+	var receivers []string
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			receivers = append(receivers, name)
+		}
+	}
+
+	for _, client := range c.Clients {
+		add(client)
+	}
+
+	var walk func(r *Route)
+	walk = func(r *Route) {
+		add(r.Receiver)
+		for _, child := range r.Routes {
+			walk(child)
+		}
+	}
+	if c.Route != nil {
+		walk(c.Route)
+	}
+	return receivers
+}
+
 // ShouldSendAlert 根据过滤规则判断是否应该发送告警
 func (c *AppConfig) ShouldSendAlert(alertName, severity string) bool {
 	// 检查告警名称过滤规则
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"alert-webhook/config"
 	"alert-webhook/service"
 	"log"
 
@@ -8,6 +9,9 @@ import (
 )
 
 func main() {
+	// 加载配置并创建通知器
+	config.Init()
+
 	// 创建并启动应用
 	app := service.NewAppLauncher()
 	app.Run()
@@ -0,0 +1,178 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// barkAPIURL Bark 官方服务地址
+const barkAPIURL = "https://api.day.app"
+
+// Bark 通知级别：critical 静音和勿扰模式下也会响铃，timeSensitive 可突破专注模式，passive 只加入通知列表不亮屏
+const (
+	barkLevelCritical      = "critical"
+	barkLevelTimeSensitive = "timeSensitive"
+	barkLevelActive        = "active"
+	barkLevelPassive       = "passive"
+)
+
+// BarkMessage Bark 推送请求体，每个设备 key 单独发送
+type BarkMessage struct {
+	DeviceKey string `json:"device_key"`
+	Title     string `json:"title,omitempty"`
+	Body      string `json:"body"`
+	Level     string `json:"level,omitempty"`
+	// critical 级别的响铃音量，0~10
+	Volume int `json:"volume,omitempty"`
+	// 为 "1" 时重复响铃30秒
+	Call  string `json:"call,omitempty"`
+	Group string `json:"group,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// BarkNotifier Bark iOS 推送通知器，支持官方服务和自建 bark-server
+type BarkNotifier struct {
+	name       string
+	sendURL    string
+	deviceKeys []string
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+// barkResponse Bark 响应体，成功时 code 为200
+type barkResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func init() {
+	Register("bark", NewBarkNotifier)
+}
+
+// NewBarkNotifier 创建 Bark 通知器，设备 key 放在请求体中，不出现在请求地址中
+func NewBarkNotifier(name string, cfg Config) (Notifier, error) {
+	if len(cfg.DeviceKeys) == 0 {
+		return nil, fmt.Errorf("接收者 %s 的 device_keys 未配置", name)
+	}
+
+	deviceKeys := make([]string, 0, len(cfg.DeviceKeys))
+	for _, key := range cfg.DeviceKeys {
+		deviceKey, err := resolveSecret(key)
+		if err != nil {
+			return nil, fmt.Errorf("接收者 %s 的 device_keys 读取失败: %w", name, err)
+		}
+		deviceKeys = append(deviceKeys, deviceKey)
+	}
+
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = barkAPIURL
+	}
+
+	n := &BarkNotifier{
+		name:       name,
+		sendURL:    strings.TrimRight(apiURL, "/") + "/push",
+		deviceKeys: deviceKeys,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkResponse)
+	return n, nil
+}
+
+func (b *BarkNotifier) Name() string {
+	return b.name
+}
+
+func (b *BarkNotifier) MaxMessageSize() int {
+	return pushMaxLength
+}
+
+// Format 标题为告警分组和数量，正文超过长度限制时分批推送，每批发送到全部设备，通知级别取每批中的最高告警级别
+func (b *BarkNotifier) Format(data template.Data) ([]Message, error) {
+	format := messageFormatter(b.template, formatPushText, b.timeFormat)
+	batches := utils.SplitAlerts(data, b.MaxMessageSize(), format.Text)
+
+	messages := make([]Message, 0, len(batches)*len(b.deviceKeys))
+	for _, batch := range batches {
+		severity := pushSeverity(batch)
+		for _, deviceKey := range b.deviceKeys {
+			message := BarkMessage{
+				DeviceKey: deviceKey,
+				Title:     pushTitle(batch),
+				Body:      format.Text(batch),
+				Level:     barkLevel(severity),
+				Group:     "Prometheus",
+				URL:       pushClickURL(batch),
+			}
+			// P0 告警以最大音量重复响铃
+			if severity == "P0" {
+				message.Volume = 10
+				message.Call = "1"
+			}
+			messages = append(messages, Message{Alerts: batch.Alerts, Payload: message})
+		}
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (b *BarkNotifier) Send(ctx context.Context, message interface{}) error {
+	return b.sender.SendAlert(ctx, b.sendURL, message)
+}
+
+// TestConnection 以 passive 级别向每个设备发送测试消息，不亮屏也不响铃
+func (b *BarkNotifier) TestConnection() error {
+	for _, deviceKey := range b.deviceKeys {
+		err := b.sender.SendTestMessage(b.sendURL, BarkMessage{
+			DeviceKey: deviceKey,
+			Title:     "Bark连通性测试",
+			Body:      "Bark连通性测试",
+			Level:     barkLevelPassive,
+			Group:     "Prometheus",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkResponse 解析 Bark 响应体中的 code
+func (b *BarkNotifier) checkResponse(body []byte) error {
+	var resp barkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Printf("[%s] 解析响应体失败，忽略错误码检查: %v", b.name, err)
+		return nil
+	}
+	if resp.Code != 0 && resp.Code != http.StatusOK {
+		return newVendorError(b.name, resp.Code, resp.Message, resp.Code == http.StatusTooManyRequests || resp.Code >= 500)
+	}
+	return nil
+}
+
+// barkLevel 将 MapSeverity 的级别映射为 Bark 通知级别，P0 为 critical，已恢复为 passive
+func barkLevel(severity string) string {
+	switch severity {
+	case "P0":
+		return barkLevelCritical
+	case "P1":
+		return barkLevelTimeSensitive
+	case "P2":
+		return barkLevelActive
+	case "P3", "":
+		return barkLevelPassive
+	default:
+		return barkLevelActive
+	}
+}
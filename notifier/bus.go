@@ -0,0 +1,196 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// busBatchSize 每批发布的告警数量，一批消息作为一次 Send 发送和重试
+const busBatchSize = 100
+
+// BusConfig 消息总线接收者配置（NATS、Kafka、Redis Streams），主题使用 topic，NATS 令牌使用 token
+type BusConfig struct {
+	// 服务地址：NATS 为 nats://host:port，Kafka 为 host:port 形式的 broker 列表，Redis 为 host:port（多个地址时为集群或哨兵）
+	Brokers []string `yaml:"brokers"`
+	// 用户名和密码（NATS 用户、Kafka SASL/PLAIN、Redis ACL），密码支持 env: 和 file: 前缀
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// 是否使用 TLS 连接
+	TLS bool `yaml:"tls"`
+	// 是否跳过 TLS 证书校验，仅用于测试环境
+	TLSSkipVerify bool `yaml:"tls_skip_verify"`
+	// Redis Stream 的近似最大长度，超出时裁剪最早的消息，0 表示不裁剪
+	StreamMaxLen int64 `yaml:"stream_max_len"`
+}
+
+// tlsConfig 返回连接使用的 TLS 配置，未启用 TLS 时返回 nil
+func (c BusConfig) tlsConfig() *tls.Config {
+	if !c.TLS {
+		return nil
+	}
+	return &tls.Config{InsecureSkipVerify: c.TLSSkipVerify}
+}
+
+// BusMessage 发布到消息总线的一条消息，每个告警一条
+type BusMessage struct {
+	// 告警指纹，同一告警的消息 key 相同
+	Key string
+	// 告警指纹、状态和开始时间，同一告警的同一次状态变化相同，用于下游去重
+	ID    string
+	Value []byte
+}
+
+// BusAlert 消息体，每个告警一个 JSON 文档
+type BusAlert struct {
+	Receiver     string            `json:"receiver"`
+	Status       string            `json:"status"`
+	Fingerprint  string            `json:"fingerprint"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"starts_at"`
+	EndsAt       time.Time         `json:"ends_at"`
+	GeneratorURL string            `json:"generator_url,omitempty"`
+	GroupLabels  map[string]string `json:"group_labels,omitempty"`
+	ExternalURL  string            `json:"external_url,omitempty"`
+}
+
+// Publisher 消息总线客户端，每种消息总线实现一次
+type Publisher interface {
+	// Publish 发布一批消息，返回后消息已被服务端确认；可重试的错误应返回 Retryable 的 SendError
+	Publish(ctx context.Context, messages []BusMessage) error
+	// Ping 检查与服务端的连接
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// PublisherFactory 根据接收者配置创建消息总线客户端，客户端应在首次发布时才连接服务端
+type PublisherFactory func(name string, cfg Config) (Publisher, error)
+
+// BusNotifier 消息总线通知器，每个告警发布为一个 JSON 文档，以告警指纹作为消息 key，
+// 供下游服务按告警订阅、去重和关联
+type BusNotifier struct {
+	name      string
+	publisher Publisher
+	sender    *Sender
+}
+
+// RegisterBus 注册一种消息总线类型
+func RegisterBus(busType string, factory PublisherFactory) {
+	Register(busType, func(name string, cfg Config) (Notifier, error) {
+		publisher, err := factory(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewBusNotifier(name, cfg, publisher), nil
+	})
+}
+
+// NewBusNotifier 使用指定的消息总线客户端创建通知器，超时和重试使用接收者的 delivery 配置
+func NewBusNotifier(name string, cfg Config, publisher Publisher) *BusNotifier {
+	return &BusNotifier{
+		name:      name,
+		publisher: publisher,
+		sender:    NewSender(name, cfg.Delivery, nil),
+	}
+}
+
+func (b *BusNotifier) Name() string {
+	return b.name
+}
+
+// MaxMessageSize 每个告警单独一条消息，不按长度分批
+func (b *BusNotifier) MaxMessageSize() int {
+	return 0
+}
+
+// Format 每个告警生成一条消息，每 busBatchSize 个告警为一批
+func (b *BusNotifier) Format(data template.Data) ([]Message, error) {
+	if len(data.Alerts) == 0 {
+		return nil, nil
+	}
+
+	batches := utils.ChunkAlerts(data, busBatchSize)
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		busMessages := make([]BusMessage, 0, len(batch.Alerts))
+		for _, alert := range batch.Alerts {
+			fingerprint := utils.AlertFingerprint(alert)
+			status := utils.AlertStatus(alert, data.Status)
+			value, err := json.Marshal(BusAlert{
+				Receiver:     b.name,
+				Status:       status,
+				Fingerprint:  fingerprint,
+				Labels:       alert.Labels,
+				Annotations:  alert.Annotations,
+				StartsAt:     alert.StartsAt,
+				EndsAt:       alert.EndsAt,
+				GeneratorURL: alert.GeneratorURL,
+				GroupLabels:  data.GroupLabels,
+				ExternalURL:  data.ExternalURL,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("[%s] JSON编码失败: %w", b.name, err)
+			}
+			busMessages = append(busMessages, BusMessage{
+				Key:   fingerprint,
+				ID:    fingerprint + "-" + status + "-" + strconv.FormatInt(alert.StartsAt.Unix(), 10),
+				Value: value,
+			})
+		}
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: busMessages})
+	}
+	return messages, nil
+}
+
+// Send 发布一批消息，每次尝试使用 delivery 配置的超时时间，可重试的错误按指数退避重试
+func (b *BusNotifier) Send(ctx context.Context, message interface{}) error {
+	messages, ok := message.([]BusMessage)
+	if !ok {
+		return &SendError{Err: fmt.Errorf("[%s] 不支持的消息类型 %T", b.name, message)}
+	}
+
+	return b.sender.Retry(ctx, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, b.sender.Timeout())
+		defer cancel()
+		return b.publisher.Publish(ctx, messages)
+	})
+}
+
+// TestConnection 只检查与服务端的连接，不发布测试消息，避免下游消费者收到非告警数据
+func (b *BusNotifier) TestConnection() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.publisher.Ping(ctx); err != nil {
+		return fmt.Errorf("[%s] 连接失败: %w", b.name, err)
+	}
+	return nil
+}
+
+// Close 关闭与服务端的连接
+func (b *BusNotifier) Close() error {
+	return b.publisher.Close()
+}
+
+// busPassword 读取消息总线密码，支持 env: 和 file: 前缀
+func busPassword(name string, cfg Config) (string, error) {
+	if cfg.Password == "" {
+		return "", nil
+	}
+	password, err := resolveSecret(cfg.Password)
+	if err != nil {
+		return "", fmt.Errorf("接收者 %s 的 password 读取失败: %w", name, err)
+	}
+	return password, nil
+}
+
+// busError 将客户端错误包装为发送错误，retryable 为 false 的错误不重试
+func busError(name string, retryable bool, err error) error {
+	return &SendError{Retryable: retryable, Err: fmt.Errorf("[%s] 发布失败: %w", name, err)}
+}
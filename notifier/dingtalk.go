@@ -0,0 +1,198 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// dingTalkMaxLength 钉钉 markdown 消息限制20000字节，留一些安全边界
+const dingTalkMaxLength = 19000
+
+// DingTalkMessage 钉钉消息结构
+type DingTalkMessage struct {
+	MsgType  string           `json:"msgtype"`
+	Markdown DingTalkMarkdown `json:"markdown"`
+	At       *DingTalkAt      `json:"at,omitempty"`
+}
+
+type DingTalkMarkdown struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// DingTalkAt @ 提醒对象，被 @ 的手机号或 userId 需要同时出现在消息文本中
+type DingTalkAt struct {
+	AtMobiles []string `json:"atMobiles,omitempty"`
+	AtUserIds []string `json:"atUserIds,omitempty"`
+	IsAtAll   bool     `json:"isAtAll,omitempty"`
+}
+
+// DingTalkNotifier 钉钉群机器人通知器
+type DingTalkNotifier struct {
+	name       string
+	webhookURL string
+	// 加签密钥，为空时不签名
+	secret     string
+	mentioner  *mentioner
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+// dingTalkRateLimitCodes 钉钉限流错误码：130101 发送速度太快而限流，130102 单个机器人发送超过每分钟上限
+var dingTalkRateLimitCodes = map[int]bool{130101: true, 130102: true}
+
+// dingTalkResponse 钉钉机器人响应体
+type dingTalkResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func init() {
+	Register("dingtalk", NewDingTalkNotifier)
+}
+
+// NewDingTalkNotifier 创建钉钉通知器
+func NewDingTalkNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+	secret, err := resolveSecret(cfg.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 secret 读取失败: %w", name, err)
+	}
+	mentioner, err := newMentioner(name, cfg.Mentions)
+	if err != nil {
+		return nil, err
+	}
+	n := &DingTalkNotifier{
+		name:       name,
+		webhookURL: cfg.WebhookURL,
+		secret:     secret,
+		mentioner:  mentioner,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkResponse)
+	return n, nil
+}
+
+func (d *DingTalkNotifier) Name() string {
+	return d.name
+}
+
+func (d *DingTalkNotifier) MaxMessageSize() int {
+	return dingTalkMaxLength
+}
+
+func (d *DingTalkNotifier) Format(data template.Data) ([]Message, error) {
+	format := messageFormatter(d.template, utils.AlertFormatDingtalk, d.timeFormat)
+	// 按包含 @ 文本的完整消息拆分，避免追加提醒后超过长度限制
+	text := func(batch template.Data) string {
+		text := format.Text(batch)
+		if mentions := d.mentioner.Mentions(batch); !mentions.Empty() {
+			text += "\n\n" + dingTalkMentionText(mentions)
+		}
+		return text
+	}
+	batches := utils.SplitAlerts(data, d.MaxMessageSize(), text)
+
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		message := DingTalkMessage{
+			MsgType: "markdown",
+			Markdown: DingTalkMarkdown{
+				Title: "Prometheus告警",
+				Text:  text(batch),
+			},
+		}
+		if mentions := d.mentioner.Mentions(batch); !mentions.Empty() {
+			message.At = &DingTalkAt{
+				AtMobiles: mentions.Mobiles,
+				AtUserIds: mentions.UserIDs,
+				IsAtAll:   mentions.All,
+			}
+		}
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: message})
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Send 发送消息，配置了加签密钥时每次请求（含重试）重新签名，避免时间戳过期
+func (d *DingTalkNotifier) Send(ctx context.Context, message interface{}) error {
+	return d.sender.Retry(ctx, func(ctx context.Context) error {
+		return d.sender.post(ctx, d.signedURL(time.Now()), message)
+	})
+}
+
+func (d *DingTalkNotifier) TestConnection() error {
+	return d.sender.SendTestMessage(d.signedURL(time.Now()), DingTalkMessage{
+		MsgType: "markdown",
+		Markdown: DingTalkMarkdown{
+			Title: "钉钉连通性测试",
+			Text:  "钉钉连通性测试",
+		},
+	})
+}
+
+// dingTalkMentionText 消息末尾的 @ 文本，钉钉只高亮提醒文本中出现的手机号和 userId
+func dingTalkMentionText(mentions Mentions) string {
+	var names []string
+	for _, mobile := range mentions.Mobiles {
+		names = append(names, "@"+mobile)
+	}
+	for _, user := range mentions.UserIDs {
+		names = append(names, "@"+user)
+	}
+	if mentions.All {
+		names = append(names, "@所有人")
+	}
+	return strings.Join(names, " ")
+}
+
+// signedURL 返回带签名的 webhook 地址：timestamp 为毫秒时间戳，
+// sign 为以 secret 为密钥对 "timestamp\nsecret" 做 HmacSHA256 后的 Base64 编码
+func (d *DingTalkNotifier) signedURL(now time.Time) string {
+	if d.secret == "" {
+		return d.webhookURL
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(d.secret))
+	mac.Write([]byte(timestamp + "\n" + d.secret))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	separator := "?"
+	if strings.Contains(d.webhookURL, "?") {
+		separator = "&"
+	}
+	return d.webhookURL + separator + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+}
+
+// checkResponse 解析钉钉响应体中的 errcode
+func (d *DingTalkNotifier) checkResponse(body []byte) error {
+	var resp dingTalkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Printf("[%s] 解析响应体失败，忽略错误码检查: %v", d.name, err)
+		return nil
+	}
+	if resp.ErrCode != 0 {
+		return newVendorError(d.name, resp.ErrCode, resp.ErrMsg, dingTalkRateLimitCodes[resp.ErrCode])
+	}
+	return nil
+}
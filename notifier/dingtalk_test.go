@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDingTalkSignedURL(t *testing.T) {
+	n, err := NewDingTalkNotifier("dingtalk", Config{
+		WebhookURL: "https://oapi.dingtalk.com/robot/send?access_token=abc",
+		Secret:     "SEC1234567890",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 签名为 Base64，其中的 +、/、= 必须经过 URL 转义
+	got := n.(*DingTalkNotifier).signedURL(time.UnixMilli(1700000000000))
+	want := "https://oapi.dingtalk.com/robot/send?access_token=abc&timestamp=1700000000000" +
+		"&sign=B9%2F6u092%2FJ1f5dgtn51GI8V%2B70dNal9Kr7VUY%2By%2B0CQ%3D"
+	if got != want {
+		t.Errorf("signedURL = %s\nwant %s", got, want)
+	}
+
+	// 未配置 access_token 参数时使用 ? 连接
+	n, err = NewDingTalkNotifier("dingtalk", Config{WebhookURL: "https://example.com/robot", Secret: "SEC1234567890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = n.(*DingTalkNotifier).signedURL(time.UnixMilli(1700000000000))
+	if want := "https://example.com/robot?timestamp=1700000000000&sign=B9%2F6u092%2FJ1f5dgtn51GI8V%2B70dNal9Kr7VUY%2By%2B0CQ%3D"; got != want {
+		t.Errorf("signedURL = %s\nwant %s", got, want)
+	}
+}
+
+func TestDingTalkUnsignedURL(t *testing.T) {
+	n, err := NewDingTalkNotifier("dingtalk", Config{WebhookURL: "https://oapi.dingtalk.com/robot/send?access_token=abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n.(*DingTalkNotifier).signedURL(time.Now()); got != "https://oapi.dingtalk.com/robot/send?access_token=abc" {
+		t.Errorf("signedURL = %s, want webhook URL unchanged", got)
+	}
+}
+
+func TestDingTalkSendSignsRequest(t *testing.T) {
+	server := newCaptureServer(t, `{"errcode":0,"errmsg":"ok"}`)
+	t.Setenv("DINGTALK_SECRET", "SEC1234567890")
+	n, err := NewDingTalkNotifier("dingtalk", Config{WebhookURL: server.URL + "/robot/send?access_token=abc", Secret: "env:DINGTALK_SECRET"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, testAlerts("HighCPU"), nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	requestURL, err := url.Parse(requests[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := requestURL.Query()
+	timestamp, sign := query.Get("timestamp"), query.Get("sign")
+	if query.Get("access_token") != "abc" || timestamp == "" {
+		t.Fatalf("query = %v", query)
+	}
+	// 服务端按收到的 timestamp 校验签名
+	mac := hmac.New(sha256.New, []byte("SEC1234567890"))
+	mac.Write([]byte(timestamp + "\nSEC1234567890"))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); sign != want {
+		t.Errorf("sign = %s, want %s", sign, want)
+	}
+}
@@ -0,0 +1,220 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// Discord webhook 限制
+const (
+	// discordMaxEmbeds 单条消息最多10个 embed
+	discordMaxEmbeds = 10
+	// discordMaxEmbedChars 单条消息全部 embed 的标题、描述、字段等文本合计不超过6000字符，留一些安全边界
+	discordMaxEmbedChars = 5800
+	// discordMaxContent 消息正文上限2000字符
+	discordMaxContent = 2000
+	// discordMaxTitle embed 标题上限256字符
+	discordMaxTitle = 256
+	// discordMaxDescription embed 描述上限4096字符
+	discordMaxDescription = 4096
+	// discordMaxFieldValue embed 字段值上限1024字符
+	discordMaxFieldValue = 1024
+)
+
+// DiscordMessage Discord webhook 消息结构，每个告警一个按级别着色的 embed
+type DiscordMessage struct {
+	Content         string                 `json:"content,omitempty"`
+	Embeds          []DiscordEmbed         `json:"embeds,omitempty"`
+	AllowedMentions DiscordAllowedMentions `json:"allowed_mentions"`
+}
+
+type DiscordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []DiscordField `json:"fields,omitempty"`
+}
+
+type DiscordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// DiscordAllowedMentions 禁止解析消息中的 @everyone 等提醒，避免标签值中的文本触发提醒
+type DiscordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// DiscordNotifier Discord webhook 通知器
+type DiscordNotifier struct {
+	name       string
+	webhookURL string
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+func init() {
+	Register("discord", NewDiscordNotifier)
+}
+
+// NewDiscordNotifier 创建 Discord 通知器
+func NewDiscordNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+	n := &DiscordNotifier{
+		name:       name,
+		webhookURL: cfg.WebhookURL,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	// Discord 以 HTTP 状态码返回错误，限流时返回 429 和 Retry-After
+	n.sender = NewSender(name, cfg.Delivery, nil)
+	return n, nil
+}
+
+func (d *DiscordNotifier) Name() string {
+	return d.name
+}
+
+func (d *DiscordNotifier) MaxMessageSize() int {
+	return discordMaxEmbedChars
+}
+
+// Format 先按 embed 数量分组，再按正文和 embed 文本长度分批，单个告警仍然超长时截断正文
+func (d *DiscordNotifier) Format(data template.Data) ([]Message, error) {
+	format := newPayloadFormatter(d.buildMessage, discordLength)
+	batches := utils.SplitAlertsLimited(data, discordMaxEmbeds, d.MaxMessageSize(), format.Size)
+
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		message := format.Payload(batch)
+		message.Content = utils.Truncate(discordMaxContent, message.Content)
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: message})
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (d *DiscordNotifier) Send(ctx context.Context, message interface{}) error {
+	return d.sender.SendAlert(ctx, d.webhookURL, message)
+}
+
+func (d *DiscordNotifier) TestConnection() error {
+	return d.sender.SendTestMessage(d.webhookURL, DiscordMessage{
+		Content:         "Discord连通性测试",
+		AllowedMentions: DiscordAllowedMentions{Parse: []string{}},
+	})
+}
+
+// buildMessage 配置了自定义模板时以模板渲染结果作为消息正文，否则每个告警生成一个 embed
+func (d *DiscordNotifier) buildMessage(batch template.Data) (DiscordMessage, error) {
+	message := DiscordMessage{AllowedMentions: DiscordAllowedMentions{Parse: []string{}}}
+
+	text, ok, err := d.template.Render(batch)
+	if err != nil {
+		return message, err
+	}
+	if ok {
+		message.Content = text
+		return message, nil
+	}
+
+	firing, resolved := utils.SplitByStatus(batch)
+	now := time.Now()
+	message.Content = alertSummary(len(firing), len(resolved))
+	for _, alert := range firing {
+		message.Embeds = append(message.Embeds, d.embed(batch, alert, "firing", now))
+	}
+	for _, alert := range resolved {
+		message.Embeds = append(message.Embeds, d.embed(batch, alert, "resolved", now))
+	}
+	return message, nil
+}
+
+// embed 生成单个告警的 embed：标题链接到图表，描述为摘要、描述和静默链接，时间等信息为并排字段
+func (d *DiscordNotifier) embed(batch template.Data, alert template.Alert, status string, now time.Time) DiscordEmbed {
+	severity := alert.Labels["severity"]
+
+	title := fmt.Sprintf("[%s] %s", utils.MapSeverity(severity), alert.Labels["alertname"])
+	fields := []DiscordField{
+		discordField("实例", alert.Labels["instance"]),
+		discordField("触发时间", d.timeFormat.Format(alert.StartsAt)),
+	}
+	if status == "resolved" {
+		title = fmt.Sprintf("[已恢复] %s", alert.Labels["alertname"])
+		fields = append(fields,
+			discordField("恢复时间", d.timeFormat.Format(alert.EndsAt)),
+			discordField("持续时间", utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now))))
+	} else {
+		fields = append(fields, discordField("已持续", utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now))))
+	}
+
+	var description []string
+	if summary := alert.Annotations["summary"]; summary != "" {
+		description = append(description, summary)
+	}
+	if desc := alert.Annotations["description"]; desc != "" && status == "firing" {
+		description = append(description, desc)
+	}
+	// 已恢复的告警无需静默
+	if silence := utils.SilenceURL(batch.ExternalURL, alert.Labels); status == "firing" && silence != "" {
+		description = append(description, "[静默]("+silence+")")
+	}
+
+	return DiscordEmbed{
+		Title:       utils.Truncate(discordMaxTitle, title),
+		URL:         alert.GeneratorURL,
+		Description: utils.Truncate(discordMaxDescription, strings.Join(description, "\n")),
+		Color:       discordColor(utils.SeverityHexColor(severity, status)),
+		Fields:      fields,
+	}
+}
+
+// discordField 生成并排显示的字段，Discord 不允许字段值为空
+func discordField(name, value string) DiscordField {
+	if value == "" {
+		value = "-"
+	}
+	return DiscordField{Name: name, Value: utils.Truncate(discordMaxFieldValue, value), Inline: true}
+}
+
+// discordColor 将 #RRGGBB 颜色转换为 Discord 使用的整数颜色
+func discordColor(hex string) int {
+	color, err := strconv.ParseInt(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil {
+		return 0
+	}
+	return int(color)
+}
+
+// discordLength 返回用于分批的消息长度：embed 文本总字符数，以及按 discordMaxEmbedChars/discordMaxContent
+// 折算后的正文字符数，两者取较大值，任一项超出各自的限制时结果都超过 discordMaxEmbedChars
+func discordLength(message DiscordMessage) int {
+	content := utf8.RuneCountInString(message.Content) * discordMaxEmbedChars / discordMaxContent
+	return max(content, discordEmbedChars(message.Embeds))
+}
+
+// discordEmbedChars 按 Discord 的计算方式统计 embed 文本总字符数
+func discordEmbedChars(embeds []DiscordEmbed) int {
+	total := 0
+	for _, embed := range embeds {
+		total += utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+		for _, field := range embed.Fields {
+			total += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		}
+	}
+	return total
+}
@@ -0,0 +1,132 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestDiscordPayload(t *testing.T) {
+	server := newCaptureServer(t, "")
+	n, err := NewDiscordNotifier("discord", Config{WebhookURL: server.URL + "/api/webhooks/1/abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := template.Data{
+		Status:      "firing",
+		ExternalURL: "http://alertmanager:9093",
+		Alerts: template.Alerts{
+			{Status: "firing", Labels: template.KV{"alertname": "HighCPU", "instance": "node-1", "severity": "critical"},
+				Annotations: template.KV{"summary": "CPU 持续升高 @everyone"}, GeneratorURL: "http://prometheus/graph"},
+			{Status: "resolved", Labels: template.KV{"alertname": "DiskFull", "severity": "warning"}},
+		},
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Path != "/api/webhooks/1/abc" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	// allowed_mentions.parse 为空数组时 Discord 不解析任何提醒
+	if !strings.Contains(string(requests[0].Body), `"allowed_mentions":{"parse":[]}`) {
+		t.Errorf("body missing empty allowed_mentions: %s", requests[0].Body)
+	}
+	var msg DiscordMessage
+	if err := json.Unmarshal(requests[0].Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg.Content, "1 个告警中，1 个已恢复") || len(msg.Embeds) != 2 {
+		t.Fatalf("message = %+v", msg)
+	}
+
+	firing, resolved := msg.Embeds[0], msg.Embeds[1]
+	if firing.Title != "[P1] HighCPU" || firing.URL != "http://prometheus/graph" || firing.Color != 0xFF7F0E {
+		t.Errorf("firing embed = %+v", firing)
+	}
+	if !strings.Contains(firing.Description, "CPU 持续升高") || !strings.Contains(firing.Description, "[静默](http://alertmanager:9093/#/silences/new") {
+		t.Errorf("firing description = %q", firing.Description)
+	}
+	if resolved.Title != "[已恢复] DiskFull" || resolved.Color != 0x2EB67D || strings.Contains(resolved.Description, "静默") {
+		t.Errorf("resolved embed = %+v", resolved)
+	}
+	// Discord 不允许字段值为空
+	for _, field := range resolved.Fields {
+		if field.Value == "" || !field.Inline {
+			t.Errorf("resolved field = %+v", field)
+		}
+	}
+	if resolved.Fields[0].Name != "实例" || resolved.Fields[0].Value != "-" {
+		t.Errorf("instance field = %+v", resolved.Fields[0])
+	}
+}
+
+func TestDiscordSplitsByEmbedCount(t *testing.T) {
+	n, err := NewDiscordNotifier("discord", Config{WebhookURL: "http://127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 25)
+	for i := range names {
+		names[i] = fmt.Sprintf("alert-%02d", i)
+	}
+	messages, err := n.Format(testAlerts(names...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("messages = %d, want 3", len(messages))
+	}
+	total := 0
+	for _, message := range messages {
+		embeds := message.Payload.(DiscordMessage).Embeds
+		if len(embeds) > discordMaxEmbeds || len(embeds) != len(message.Alerts) {
+			t.Errorf("embeds = %d, alerts = %d", len(embeds), len(message.Alerts))
+		}
+		total += len(embeds)
+	}
+	if total != 25 {
+		t.Errorf("embeds = %d, want one per alert", total)
+	}
+}
+
+func TestDiscordSplitsByEmbedChars(t *testing.T) {
+	n, err := NewDiscordNotifier("discord", Config{WebhookURL: "http://127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := testAlerts("a", "b", "c", "d", "e", "f")
+	for i := range data.Alerts {
+		data.Alerts[i].Annotations = template.KV{"description": strings.Repeat("告", 1500)}
+	}
+	// 单个告警的描述超过 embed 上限时截断
+	data.Alerts[5].Annotations["description"] = strings.Repeat("告", 5000)
+
+	messages, err := n.Format(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) < 2 {
+		t.Fatalf("messages = %d, want alerts split by embed characters", len(messages))
+	}
+	for i, message := range messages {
+		embeds := message.Payload.(DiscordMessage).Embeds
+		if chars := discordEmbedChars(embeds); chars > discordMaxEmbedChars {
+			t.Errorf("message %d embed chars = %d, exceeds %d", i, chars, discordMaxEmbedChars)
+		}
+		for _, embed := range embeds {
+			if utf8.RuneCountInString(embed.Description) > discordMaxDescription {
+				t.Errorf("message %d description = %d chars, exceeds %d", i, utf8.RuneCountInString(embed.Description), discordMaxDescription)
+			}
+		}
+	}
+}
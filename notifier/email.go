@@ -0,0 +1,480 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// SMTP 连接加密方式
+const (
+	smtpTLSStartTLS = "starttls"
+	smtpTLSImplicit = "tls"
+	smtpTLSNone     = "none"
+)
+
+// defaultEmailSubject 未配置 email_subject 时使用的邮件主题模板
+const defaultEmailSubject = `{{ if .Alerts.Firing }}[告警中:{{ len .Alerts.Firing }}]{{ end }}{{ if .Alerts.Resolved }}[已恢复:{{ len .Alerts.Resolved }}]{{ end }} {{ join ", " .GroupLabels.Values }}`
+
+// EmailConfig 邮件接收者配置
+type EmailConfig struct {
+	SMTPHost string `yaml:"smtp_host"`
+	// SMTP 端口，未配置时按加密方式使用 587（starttls）、465（tls）或 25（none）
+	SMTPPort int `yaml:"smtp_port"`
+	// 加密方式：starttls（默认）、tls（SMTPS）、none
+	SMTPTLS string `yaml:"smtp_tls"`
+	// 跳过服务器证书校验，仅用于测试环境
+	SMTPSkipVerify bool `yaml:"smtp_skip_verify"`
+	// 认证用户名和密码，用户名为空时不认证；密码支持 env: 和 file: 前缀
+	// PLAIN 认证要求加密连接，smtp_tls 为 none 时只能认证 localhost 上的服务器
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+
+	EmailFrom string   `yaml:"email_from"`
+	EmailTo   []string `yaml:"email_to"`
+	EmailCc   []string `yaml:"email_cc"`
+	// 邮件主题模板（Go text/template），可使用与自定义消息模板相同的辅助函数
+	EmailSubject string `yaml:"email_subject"`
+}
+
+// EmailMessage 一封告警邮件，包含纯文本和 HTML 两种正文
+type EmailMessage struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// EmailNotifier SMTP 邮件通知器，同一次推送的全部告警合并为一封邮件
+type EmailNotifier struct {
+	name       string
+	cfg        EmailConfig
+	addr       string
+	from       *mail.Address
+	recipients []string
+	subject    *texttemplate.Template
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+func init() {
+	Register("email", NewEmailNotifier)
+}
+
+// NewEmailNotifier 创建邮件通知器
+func NewEmailNotifier(name string, cfg Config) (Notifier, error) {
+	email := cfg.EmailConfig
+	if email.SMTPHost == "" {
+		return nil, fmt.Errorf("接收者 %s 的 smtp_host 未配置", name)
+	}
+	if email.EmailFrom == "" {
+		return nil, fmt.Errorf("接收者 %s 的 email_from 未配置", name)
+	}
+	if len(email.EmailTo) == 0 {
+		return nil, fmt.Errorf("接收者 %s 的 email_to 未配置", name)
+	}
+
+	// 信封地址只使用邮箱部分，邮件头中保留显示名称
+	from, err := mail.ParseAddress(email.EmailFrom)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 email_from %s 格式错误: %w", name, email.EmailFrom, err)
+	}
+	var recipients []string
+	for _, rcpt := range append(append([]string{}, email.EmailTo...), email.EmailCc...) {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return nil, fmt.Errorf("接收者 %s 的收件人地址 %s 格式错误: %w", name, rcpt, err)
+		}
+		recipients = append(recipients, addr.Address)
+	}
+
+	email.SMTPTLS = strings.ToLower(email.SMTPTLS)
+	defaultPort := 587
+	switch email.SMTPTLS {
+	case "", smtpTLSStartTLS:
+		email.SMTPTLS = smtpTLSStartTLS
+	case smtpTLSImplicit:
+		defaultPort = 465
+	case smtpTLSNone:
+		defaultPort = 25
+	default:
+		return nil, fmt.Errorf("接收者 %s 的 smtp_tls %s 不受支持，可选: starttls、tls、none", name, email.SMTPTLS)
+	}
+	if email.SMTPPort == 0 {
+		email.SMTPPort = defaultPort
+	}
+	// net/smtp 的 PLAIN 认证拒绝在未加密连接上发送密码（localhost 除外），启动时提前报错
+	if email.SMTPTLS == smtpTLSNone && email.SMTPUsername != "" && !isLocalSMTPHost(email.SMTPHost) {
+		return nil, fmt.Errorf("接收者 %s 配置了 smtp_username，smtp_tls 为 none 时只能认证 localhost 上的SMTP服务器，请使用 starttls 或 tls", name)
+	}
+
+	password, err := resolveSecret(email.SMTPPassword)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 smtp_password 读取失败: %w", name, err)
+	}
+	email.SMTPPassword = password
+
+	timeFormat := cfg.timeFormat()
+	subject := email.EmailSubject
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+	subjectTemplate, err := texttemplate.New("email_subject").Funcs(utils.TemplateFuncs(timeFormat)).Option("missingkey=zero").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 email_subject 解析失败: %w", name, err)
+	}
+
+	return &EmailNotifier{
+		name:       name,
+		cfg:        email,
+		addr:       net.JoinHostPort(email.SMTPHost, strconv.Itoa(email.SMTPPort)),
+		from:       from,
+		recipients: recipients,
+		subject:    subjectTemplate,
+		sender:     NewSender(name, cfg.Delivery, nil),
+		template:   cfg.MessageTemplate,
+		timeFormat: timeFormat,
+	}, nil
+}
+
+func (e *EmailNotifier) Name() string {
+	return e.name
+}
+
+// MaxMessageSize 邮件不限制长度，同一次推送只发送一封
+func (e *EmailNotifier) MaxMessageSize() int {
+	return 0
+}
+
+// Format 生成一封邮件：主题模板、纯文本正文和 HTML 正文，配置了自定义模板时以模板渲染结果作为正文
+func (e *EmailNotifier) Format(data template.Data) ([]Message, error) {
+	var subject bytes.Buffer
+	if err := e.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("[%s] 渲染邮件主题失败: %w", e.name, err)
+	}
+
+	message := EmailMessage{
+		// 主题不能包含换行
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+	}
+
+	text, ok, err := e.template.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		message.Text = text
+		message.HTML = "<pre>" + html.EscapeString(text) + "</pre>"
+	} else {
+		message.Text = e.formatText(data)
+		message.HTML = e.formatHTML(data)
+	}
+	return []Message{{Alerts: data.Alerts, Payload: message}}, nil
+}
+
+func (e *EmailNotifier) Send(ctx context.Context, message interface{}) error {
+	msg, ok := message.(EmailMessage)
+	if !ok {
+		return &SendError{Err: fmt.Errorf("[%s] 不支持的消息类型 %T", e.name, message)}
+	}
+	body, err := e.buildMIME(msg)
+	if err != nil {
+		return &SendError{Err: fmt.Errorf("[%s] 生成邮件失败: %w", e.name, err)}
+	}
+	return e.sender.Retry(ctx, func(ctx context.Context) error {
+		return e.send(ctx, body)
+	})
+}
+
+// TestConnection 连接 SMTP 服务器并完成加密和认证，不发送邮件
+func (e *EmailNotifier) TestConnection() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := e.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Quit()
+}
+
+// dial 连接 SMTP 服务器，按配置完成 TLS/STARTTLS 和认证
+func (e *EmailNotifier) dial(ctx context.Context) (*smtp.Client, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(e.sender.Timeout())
+	}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return nil, e.smtpError("连接SMTP服务器", err)
+	}
+	// 整个 SMTP 会话共用一个截止时间
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, e.smtpError("设置连接超时", err)
+	}
+
+	tlsConfig := &tls.Config{ServerName: e.cfg.SMTPHost, InsecureSkipVerify: e.cfg.SMTPSkipVerify}
+	if e.cfg.SMTPTLS == smtpTLSImplicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, e.cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return nil, e.smtpError("建立SMTP会话", err)
+	}
+
+	if e.cfg.SMTPTLS == smtpTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, &SendError{Err: fmt.Errorf("[%s] SMTP服务器不支持STARTTLS，可配置 smtp_tls: none", e.name)}
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, e.smtpError("STARTTLS", err)
+		}
+	}
+
+	if e.cfg.SMTPUsername != "" {
+		auth := smtp.PlainAuth("", e.cfg.SMTPUsername, e.cfg.SMTPPassword, e.cfg.SMTPHost)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, e.smtpError("SMTP认证", err)
+		}
+	}
+	return client, nil
+}
+
+// send 发送一次邮件到全部收件人和抄送人
+func (e *EmailNotifier) send(ctx context.Context, body []byte) error {
+	client, err := e.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(e.from.Address); err != nil {
+		return e.smtpError("设置发件人", err)
+	}
+	for _, rcpt := range e.recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return e.smtpError("设置收件人 "+rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return e.smtpError("发送邮件内容", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return e.smtpError("发送邮件内容", err)
+	}
+	if err := w.Close(); err != nil {
+		return e.smtpError("发送邮件内容", err)
+	}
+	return client.Quit()
+}
+
+// smtpError 对 SMTP 错误分类：4xx 响应和网络错误可重试，5xx 响应为永久错误
+func (e *EmailNotifier) smtpError(op string, err error) error {
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return &SendError{
+			Retryable: tpErr.Code >= 400 && tpErr.Code < 500,
+			Err:       fmt.Errorf("[%s] %s失败: %w", e.name, op, &VendorError{Code: tpErr.Code, Message: tpErr.Msg}),
+		}
+	}
+	return &SendError{Retryable: true, Err: fmt.Errorf("[%s] %s失败: %w", e.name, op, err)}
+}
+
+// buildMIME 生成 multipart/alternative 邮件，纯文本在前、HTML 在后
+func (e *EmailNotifier) buildMIME(msg EmailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	headers := []string{
+		"From: " + e.from.String(),
+		"To: " + strings.Join(e.cfg.EmailTo, ", "),
+	}
+	if len(e.cfg.EmailCc) > 0 {
+		headers = append(headers, "Cc: "+strings.Join(e.cfg.EmailCc, ", "))
+	}
+	headers = append(headers,
+		"Subject: "+mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: "+time.Now().Format(time.RFC1123Z),
+		"Message-ID: "+e.messageID(),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary="+mw.Boundary(),
+	)
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// messageID 生成邮件的 Message-ID
+func (e *EmailNotifier) messageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	domain := e.cfg.SMTPHost
+	if _, after, ok := strings.Cut(e.from.Address, "@"); ok {
+		domain = after
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// formatText 邮件纯文本正文，告警中和已恢复分为两部分
+func (e *EmailNotifier) formatText(data template.Data) string {
+	var builder strings.Builder
+	now := time.Now()
+	firing, resolved := utils.SplitByStatus(data)
+
+	if len(firing) > 0 {
+		builder.WriteString(fmt.Sprintf("Prometheus告警通知（%d 个告警中）\n", len(firing)))
+		for _, alert := range firing {
+			builder.WriteString("\n")
+			builder.WriteString(fmt.Sprintf("告警名称: %s\n", alert.Labels["alertname"]))
+			builder.WriteString(fmt.Sprintf("级别: %s\n", utils.MapSeverity(alert.Labels["severity"])))
+			builder.WriteString(fmt.Sprintf("实例: %s\n", alert.Labels["instance"]))
+			builder.WriteString(fmt.Sprintf("摘要: %s\n", alert.Annotations["summary"]))
+			if desc := alert.Annotations["description"]; desc != "" {
+				builder.WriteString(fmt.Sprintf("描述: %s\n", desc))
+			}
+			builder.WriteString(fmt.Sprintf("触发时间: %s\n", e.timeFormat.Format(alert.StartsAt)))
+			builder.WriteString(fmt.Sprintf("已持续: %s\n", utils.FormatDuration(utils.AlertDuration(alert, data.Status, now))))
+			if alert.GeneratorURL != "" {
+				builder.WriteString(fmt.Sprintf("查看图表: %s\n", alert.GeneratorURL))
+			}
+		}
+	}
+
+	if len(resolved) > 0 {
+		if len(firing) > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fmt.Sprintf("Prometheus告警恢复（%d 个已恢复）\n", len(resolved)))
+		for _, alert := range resolved {
+			builder.WriteString("\n")
+			builder.WriteString(fmt.Sprintf("告警名称: %s\n", alert.Labels["alertname"]))
+			builder.WriteString(fmt.Sprintf("实例: %s\n", alert.Labels["instance"]))
+			builder.WriteString(fmt.Sprintf("恢复时间: %s\n", e.timeFormat.Format(alert.EndsAt)))
+			builder.WriteString(fmt.Sprintf("持续时间: %s\n", utils.FormatDuration(utils.AlertDuration(alert, data.Status, now))))
+		}
+	}
+	return builder.String()
+}
+
+// formatHTML 邮件 HTML 正文，告警中和已恢复各一个表格，级别列按告警级别着色
+func (e *EmailNotifier) formatHTML(data template.Data) string {
+	var builder strings.Builder
+	now := time.Now()
+	firing, resolved := utils.SplitByStatus(data)
+	esc := html.EscapeString
+
+	builder.WriteString(`<html><body style="font-family:sans-serif;font-size:14px">`)
+	table := func(headers []string) {
+		builder.WriteString(`<table cellpadding="6" cellspacing="0" border="1" style="border-collapse:collapse;border-color:#ddd">`)
+		builder.WriteString(`<tr style="background:#f5f5f5">`)
+		for _, h := range headers {
+			builder.WriteString("<th>" + esc(h) + "</th>")
+		}
+		builder.WriteString("</tr>")
+	}
+
+	if len(firing) > 0 {
+		builder.WriteString(fmt.Sprintf("<h3>🔥 Prometheus告警通知（%d 个告警中）</h3>", len(firing)))
+		table([]string{"级别", "告警名称", "实例", "摘要", "描述", "触发时间", "已持续", "链接"})
+		for _, alert := range firing {
+			severity := alert.Labels["severity"]
+			link := ""
+			if alert.GeneratorURL != "" {
+				link = `<a href="` + esc(alert.GeneratorURL) + `">查看图表</a>`
+			}
+			builder.WriteString("<tr>")
+			builder.WriteString(fmt.Sprintf(`<td style="color:#fff;background:%s"><b>%s</b></td>`,
+				utils.SeverityHexColor(severity, "firing"), esc(utils.MapSeverity(severity))))
+			for _, cell := range []string{
+				alert.Labels["alertname"],
+				alert.Labels["instance"],
+				alert.Annotations["summary"],
+				alert.Annotations["description"],
+				e.timeFormat.Format(alert.StartsAt),
+				utils.FormatDuration(utils.AlertDuration(alert, data.Status, now)),
+			} {
+				builder.WriteString("<td>" + esc(cell) + "</td>")
+			}
+			builder.WriteString("<td>" + link + "</td></tr>")
+		}
+		builder.WriteString("</table>")
+	}
+
+	if len(resolved) > 0 {
+		builder.WriteString(fmt.Sprintf("<h3>✅ Prometheus告警恢复（%d 个已恢复）</h3>", len(resolved)))
+		table([]string{"告警名称", "实例", "恢复时间", "持续时间"})
+		for _, alert := range resolved {
+			builder.WriteString("<tr>")
+			for _, cell := range []string{
+				alert.Labels["alertname"],
+				alert.Labels["instance"],
+				e.timeFormat.Format(alert.EndsAt),
+				utils.FormatDuration(utils.AlertDuration(alert, data.Status, now)),
+			} {
+				builder.WriteString("<td>" + esc(cell) + "</td>")
+			}
+			builder.WriteString("</tr>")
+		}
+		builder.WriteString("</table>")
+	}
+
+	builder.WriteString("</body></html>")
+	return builder.String()
+}
+
+// isLocalSMTPHost 判断是否为 net/smtp 允许未加密 PLAIN 认证的本机地址
+func isLocalSMTPHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
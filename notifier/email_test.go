@@ -0,0 +1,244 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// smtpEnvelope SMTP 模拟服务收到的一封邮件
+type smtpEnvelope struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// smtpSink 最小的 SMTP 模拟服务，不支持 STARTTLS 和认证，用于 smtp_tls: none
+type smtpSink struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	mails []smtpEnvelope
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpSink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(textproto.NewConn(conn))
+	}
+}
+
+func (s *smtpSink) handle(conn *textproto.Conn) {
+	defer conn.Close()
+	var envelope smtpEnvelope
+	conn.PrintfLine("220 localhost ESMTP sink")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			conn.PrintfLine("250 localhost")
+		case "MAIL":
+			envelope = smtpEnvelope{From: smtpPath(arg)}
+			conn.PrintfLine("250 OK")
+		case "RCPT":
+			envelope.To = append(envelope.To, smtpPath(arg))
+			conn.PrintfLine("250 OK")
+		case "DATA":
+			conn.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			envelope.Data = data
+			s.mu.Lock()
+			s.mails = append(s.mails, envelope)
+			s.mu.Unlock()
+			conn.PrintfLine("250 OK")
+		case "QUIT":
+			conn.PrintfLine("221 Bye")
+			return
+		default:
+			conn.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// smtpPath 取出 MAIL FROM:<a@b> 和 RCPT TO:<a@b> 中的地址
+func smtpPath(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	return strings.Trim(path, "<>")
+}
+
+// Mails 返回已收到的邮件
+func (s *smtpSink) Mails() []smtpEnvelope {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpEnvelope(nil), s.mails...)
+}
+
+func TestEmailMultipartMessage(t *testing.T) {
+	sink := newSMTPSink(t)
+	host, port, _ := net.SplitHostPort(sink.listener.Addr().String())
+	smtpPort, _ := strconv.Atoi(port)
+
+	n, err := NewEmailNotifier("oncall-mail", Config{EmailConfig: EmailConfig{
+		SMTPHost:     host,
+		SMTPPort:     smtpPort,
+		SMTPTLS:      "none",
+		EmailFrom:    "Alertmanager <alert@example.com>",
+		EmailTo:      []string{"ops@example.com"},
+		EmailCc:      []string{"Lead <lead@example.com>"},
+		EmailSubject: `{{ .CommonLabels.alertname }} 告警`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := template.Data{
+		Status:       "firing",
+		CommonLabels: template.KV{"alertname": "HighCPU"},
+		Alerts: template.Alerts{{
+			Status:      "firing",
+			Labels:      template.KV{"alertname": "HighCPU", "instance": "node-1", "severity": "critical"},
+			Annotations: template.KV{"summary": "CPU <90%> 持续升高"},
+			StartsAt:    time.Now().Add(-time.Minute),
+		}},
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	mails := sink.Mails()
+	if len(mails) != 1 {
+		t.Fatalf("mails = %d, want 1", len(mails))
+	}
+	envelope := mails[0]
+	if envelope.From != "alert@example.com" || strings.Join(envelope.To, ",") != "ops@example.com,lead@example.com" {
+		t.Errorf("envelope = %s -> %v", envelope.From, envelope.To)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(envelope.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "HighCPU 告警" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+	if msg.Header.Get("Cc") != "Lead <lead@example.com>" || !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("headers = %v", msg.Header)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %s, %v", mediaType, err)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		// NextRawPart 不会自动解码 quoted-printable，便于检查传输编码
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.Header.Get("Content-Transfer-Encoding") != "quoted-printable" {
+			t.Errorf("part encoding = %q", part.Header.Get("Content-Transfer-Encoding"))
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, part.Header.Get("Content-Type")+"\n"+string(body))
+	}
+
+	if len(parts) != 2 {
+		t.Fatalf("parts = %d, want text/plain and text/html", len(parts))
+	}
+	if !strings.HasPrefix(parts[0], "text/plain") || !strings.Contains(parts[0], "CPU <90%> 持续升高") {
+		t.Errorf("text part = %s", parts[0])
+	}
+	if !strings.HasPrefix(parts[1], "text/html") || !strings.Contains(parts[1], "CPU &lt;90%&gt; 持续升高") {
+		t.Errorf("html part = %s", parts[1])
+	}
+}
+
+func TestEmailRejectsAuthOverPlainRemoteSMTP(t *testing.T) {
+	cfg := EmailConfig{
+		SMTPHost:     "smtp.example.com",
+		SMTPTLS:      "none",
+		SMTPUsername: "alert",
+		SMTPPassword: "secret",
+		EmailFrom:    "alert@example.com",
+		EmailTo:      []string{"ops@example.com"},
+	}
+	if _, err := NewEmailNotifier("mail", Config{EmailConfig: cfg}); err == nil {
+		t.Error("expected error for smtp_username with smtp_tls none on a remote host")
+	}
+
+	cfg.SMTPHost = "localhost"
+	if _, err := NewEmailNotifier("mail", Config{EmailConfig: cfg}); err != nil {
+		t.Errorf("localhost relay: %v", err)
+	}
+}
+
+func TestEmailPasswordFromEnv(t *testing.T) {
+	t.Setenv("SMTP_PASSWORD", "from-env")
+	n, err := NewEmailNotifier("mail", Config{EmailConfig: EmailConfig{
+		SMTPHost:     "smtp.example.com",
+		SMTPUsername: "alert",
+		SMTPPassword: "env:SMTP_PASSWORD",
+		EmailFrom:    "alert@example.com",
+		EmailTo:      []string{"ops@example.com"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n.(*EmailNotifier).cfg.SMTPPassword; got != "from-env" {
+		t.Errorf("password = %q, want value of SMTP_PASSWORD", got)
+	}
+
+	_, err = NewEmailNotifier("mail", Config{EmailConfig: EmailConfig{
+		SMTPHost:     "smtp.example.com",
+		SMTPPassword: "env:SMTP_PASSWORD_MISSING",
+		EmailFrom:    "alert@example.com",
+		EmailTo:      []string{"ops@example.com"},
+	}})
+	if err == nil {
+		t.Error("expected error for unset password variable")
+	}
+}
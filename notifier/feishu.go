@@ -0,0 +1,385 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// feishuMaxLength 飞书自定义机器人请求体限制20KB，留一些安全边界
+const feishuMaxLength = 19000
+
+// 飞书消息类型
+const (
+	feishuMsgText        = "text"
+	feishuMsgInteractive = "interactive"
+)
+
+// FeishuConfig 飞书接收者配置
+type FeishuConfig struct {
+	// 消息类型：text（默认）或 interactive（消息卡片）
+	MsgType string `yaml:"msg_type"`
+}
+
+// FeishuMessage 飞书消息结构，text 类型使用 Content，interactive 类型使用 Card
+// 配置了加签密钥时 Timestamp 和 Sign 在每次发送时填充
+type FeishuMessage struct {
+	MsgType   string         `json:"msg_type"`
+	Content   *FeishuContent `json:"content,omitempty"`
+	Card      *FeishuCard    `json:"card,omitempty"`
+	Timestamp string         `json:"timestamp,omitempty"`
+	Sign      string         `json:"sign,omitempty"`
+}
+
+type FeishuContent struct {
+	Text string `json:"text"`
+}
+
+// FeishuCard 飞书消息卡片，标题颜色按告警级别区分
+type FeishuCard struct {
+	Config   FeishuCardConfig    `json:"config"`
+	Header   FeishuCardHeader    `json:"header"`
+	Elements []FeishuCardElement `json:"elements"`
+}
+
+type FeishuCardConfig struct {
+	WideScreenMode bool `json:"wide_screen_mode"`
+}
+
+type FeishuCardHeader struct {
+	Template string         `json:"template"`
+	Title    FeishuCardText `json:"title"`
+}
+
+// FeishuCardText 卡片文本，Tag 为 plain_text 或 lark_md
+type FeishuCardText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+// FeishuCardElement 卡片元素，div 使用 Text 或 Fields，action 使用 Actions，hr 为分割线
+type FeishuCardElement struct {
+	Tag     string              `json:"tag"`
+	Text    *FeishuCardText     `json:"text,omitempty"`
+	Fields  []FeishuCardField   `json:"fields,omitempty"`
+	Actions []FeishuCardElement `json:"actions,omitempty"`
+	URL     string              `json:"url,omitempty"`
+	Type    string              `json:"type,omitempty"`
+}
+
+// FeishuCardField div 中的字段，IsShort 为 true 时两列并排显示
+type FeishuCardField struct {
+	IsShort bool           `json:"is_short"`
+	Text    FeishuCardText `json:"text"`
+}
+
+// FeishuNotifier 飞书群机器人通知器
+type FeishuNotifier struct {
+	name       string
+	webhookURL string
+	msgType    string
+	// 加签密钥，为空时不签名
+	secret     string
+	mentioner  *mentioner
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+// feishuRateLimitCodes 飞书限流错误码：9499 请求过于频繁，11232 消息发送频率超限
+var feishuRateLimitCodes = map[int]bool{9499: true, 11232: true}
+
+// feishuResponse 飞书机器人响应体，旧版接口使用 StatusCode/StatusMessage
+type feishuResponse struct {
+	Code          int    `json:"code"`
+	Msg           string `json:"msg"`
+	StatusCode    int    `json:"StatusCode"`
+	StatusMessage string `json:"StatusMessage"`
+}
+
+func init() {
+	Register("feishu", NewFeishuNotifier)
+}
+
+// NewFeishuNotifier 创建飞书通知器
+func NewFeishuNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+
+	msgType := strings.ToLower(cfg.FeishuConfig.MsgType)
+	switch msgType {
+	case "":
+		msgType = feishuMsgText
+	case feishuMsgText, feishuMsgInteractive:
+	default:
+		return nil, fmt.Errorf("接收者 %s 的 msg_type %s 不受支持，可选: text、interactive", name, cfg.FeishuConfig.MsgType)
+	}
+
+	secret, err := resolveSecret(cfg.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 secret 读取失败: %w", name, err)
+	}
+
+	mentioner, err := newMentioner(name, cfg.Mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &FeishuNotifier{
+		name:       name,
+		webhookURL: cfg.WebhookURL,
+		msgType:    msgType,
+		secret:     secret,
+		mentioner:  mentioner,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkResponse)
+	return n, nil
+}
+
+func (f *FeishuNotifier) Name() string {
+	return f.name
+}
+
+func (f *FeishuNotifier) MaxMessageSize() int {
+	return feishuMaxLength
+}
+
+func (f *FeishuNotifier) Format(data template.Data) ([]Message, error) {
+	if f.msgType == feishuMsgInteractive {
+		return f.formatCards(data)
+	}
+
+	format := messageFormatter(f.template, utils.AlertFormatFeishu, f.timeFormat)
+	// 按包含 at 标签的完整消息拆分，避免追加提醒后超过长度限制
+	text := func(batch template.Data) string {
+		text := format.Text(batch)
+		if mentions := f.mentioner.Mentions(batch); !mentions.Empty() {
+			text += "\n" + feishuMentionText(mentions, `<at user_id="%s">%s</at>`)
+		}
+		return text
+	}
+	batches := utils.SplitAlerts(data, f.MaxMessageSize(), text)
+
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: FeishuMessage{
+			MsgType: feishuMsgText,
+			Content: &FeishuContent{
+				Text: text(batch),
+			},
+		}})
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Send 发送消息，配置了加签密钥时每次请求（含重试）重新签名，避免时间戳过期
+func (f *FeishuNotifier) Send(ctx context.Context, message interface{}) error {
+	msg, ok := message.(FeishuMessage)
+	if !ok {
+		return f.sender.SendAlert(ctx, f.webhookURL, message)
+	}
+	return f.sender.Retry(ctx, func(ctx context.Context) error {
+		return f.sender.post(ctx, f.webhookURL, f.sign(msg, time.Now()))
+	})
+}
+
+func (f *FeishuNotifier) TestConnection() error {
+	return f.sender.SendTestMessage(f.webhookURL, f.sign(FeishuMessage{
+		MsgType: feishuMsgText,
+		Content: &FeishuContent{
+			Text: "飞书连通性测试",
+		},
+	}, time.Now()))
+}
+
+// sign 填充签名字段：timestamp 为秒级时间戳，sign 为以 "timestamp\nsecret" 为密钥对空字符串做 HmacSHA256 后的 Base64 编码
+func (f *FeishuNotifier) sign(msg FeishuMessage, now time.Time) FeishuMessage {
+	if f.secret == "" {
+		return msg
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+f.secret))
+	msg.Timestamp = timestamp
+	msg.Sign = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return msg
+}
+
+// checkResponse 解析飞书响应体中的 code
+func (f *FeishuNotifier) checkResponse(body []byte) error {
+	var resp feishuResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Printf("[%s] 解析响应体失败，忽略错误码检查: %v", f.name, err)
+		return nil
+	}
+	if resp.Code != 0 {
+		return newVendorError(f.name, resp.Code, resp.Msg, feishuRateLimitCodes[resp.Code])
+	}
+	if resp.StatusCode != 0 {
+		return newVendorError(f.name, resp.StatusCode, resp.StatusMessage, feishuRateLimitCodes[resp.StatusCode])
+	}
+	return nil
+}
+
+// formatCards 按请求体长度分批，每批生成一张消息卡片
+func (f *FeishuNotifier) formatCards(data template.Data) ([]Message, error) {
+	format := newPayloadFormatter(f.buildCard, jsonLength[FeishuMessage])
+	return format.Messages(utils.SplitAlertsFunc(data, f.MaxMessageSize(), format.Size))
+}
+
+// buildCard 生成消息卡片，标题颜色取本批告警中的最高级别；配置了自定义模板时以模板渲染结果作为卡片内容
+func (f *FeishuNotifier) buildCard(batch template.Data) (FeishuMessage, error) {
+	firing, resolved := utils.SplitByStatus(batch)
+	card := &FeishuCard{
+		Config: FeishuCardConfig{WideScreenMode: true},
+		Header: FeishuCardHeader{
+			Template: feishuHeaderColor(firing),
+			Title:    FeishuCardText{Tag: "plain_text", Content: alertSummary(len(firing), len(resolved))},
+		},
+	}
+
+	text, ok, err := f.template.Render(batch)
+	if err != nil {
+		return FeishuMessage{}, err
+	}
+	if ok {
+		card.Elements = []FeishuCardElement{{Tag: "div", Text: &FeishuCardText{Tag: "lark_md", Content: text}}}
+	} else {
+		now := time.Now()
+		for _, alert := range firing {
+			card.Elements = append(card.Elements, f.alertElements(batch, alert, "firing", now)...)
+		}
+		for _, alert := range resolved {
+			card.Elements = append(card.Elements, f.alertElements(batch, alert, "resolved", now)...)
+		}
+	}
+	// 去掉最后一个告警后的分割线
+	if n := len(card.Elements); n > 0 && card.Elements[n-1].Tag == "hr" {
+		card.Elements = card.Elements[:n-1]
+	}
+	if mentions := f.mentioner.Mentions(batch); !mentions.Empty() {
+		card.Elements = append(card.Elements, FeishuCardElement{
+			Tag:  "div",
+			Text: &FeishuCardText{Tag: "lark_md", Content: feishuMentionText(mentions, "<at id=%s>%s</at>")},
+		})
+	}
+	return FeishuMessage{MsgType: feishuMsgInteractive, Card: card}, nil
+}
+
+// alertElements 生成单个告警的卡片元素：标题、字段、摘要描述、查看图表和静默按钮，以分割线结尾
+func (f *FeishuNotifier) alertElements(batch template.Data, alert template.Alert, status string, now time.Time) []FeishuCardElement {
+	severity := utils.MapSeverity(alert.Labels["severity"])
+
+	title := fmt.Sprintf("**[%s] %s**", feishuEscape(severity), feishuEscape(alert.Labels["alertname"]))
+	fields := []FeishuCardField{
+		feishuField("实例", alert.Labels["instance"]),
+		feishuField("触发时间", f.timeFormat.Format(alert.StartsAt)),
+	}
+	if status == "resolved" {
+		title = fmt.Sprintf("**[已恢复] %s**", feishuEscape(alert.Labels["alertname"]))
+		fields = append(fields,
+			feishuField("恢复时间", f.timeFormat.Format(alert.EndsAt)),
+			feishuField("持续时间", utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now))))
+	} else {
+		fields = append(fields,
+			feishuField("级别", severity),
+			feishuField("已持续", utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now))))
+	}
+
+	elements := []FeishuCardElement{
+		{Tag: "div", Text: &FeishuCardText{Tag: "lark_md", Content: title}},
+		{Tag: "div", Fields: fields},
+	}
+
+	var details []string
+	if summary := alert.Annotations["summary"]; summary != "" {
+		details = append(details, "**摘要:** "+feishuEscape(summary))
+	}
+	if desc := alert.Annotations["description"]; desc != "" && status == "firing" {
+		details = append(details, "**描述:** "+feishuEscape(desc))
+	}
+	if len(details) > 0 {
+		elements = append(elements, FeishuCardElement{Tag: "div", Text: &FeishuCardText{Tag: "lark_md", Content: strings.Join(details, "\n")}})
+	}
+
+	var buttons []FeishuCardElement
+	if alert.GeneratorURL != "" {
+		buttons = append(buttons, feishuButton("查看图表", alert.GeneratorURL, "primary"))
+	}
+	// 已恢复的告警无需静默
+	if silence := utils.SilenceURL(batch.ExternalURL, alert.Labels); status == "firing" && silence != "" {
+		buttons = append(buttons, feishuButton("静默", silence, "danger"))
+	}
+	if len(buttons) > 0 {
+		elements = append(elements, FeishuCardElement{Tag: "action", Actions: buttons})
+	}
+
+	return append(elements, FeishuCardElement{Tag: "hr"})
+}
+
+func feishuField(name, value string) FeishuCardField {
+	return FeishuCardField{
+		IsShort: true,
+		Text:    FeishuCardText{Tag: "lark_md", Content: "**" + name + ":**\n" + feishuEscape(value)},
+	}
+}
+
+func feishuButton(text, url, buttonType string) FeishuCardElement {
+	return FeishuCardElement{
+		Tag:  "button",
+		Text: &FeishuCardText{Tag: "plain_text", Content: text},
+		URL:  url,
+		Type: buttonType,
+	}
+}
+
+// feishuHeaderColor 卡片标题颜色：取告警中的最高级别，全部已恢复时为绿色
+func feishuHeaderColor(firing []template.Alert) string {
+	if len(firing) == 0 {
+		return "green"
+	}
+
+	colors := map[string]string{"P0": "red", "P1": "orange", "P2": "yellow", "P3": "blue"}
+	ranks := map[string]int{"P0": 0, "P1": 1, "P2": 2, "P3": 3}
+	color, rank := "grey", len(ranks)
+	for _, alert := range firing {
+		severity := utils.MapSeverity(alert.Labels["severity"])
+		if r, ok := ranks[severity]; ok && r < rank {
+			color, rank = colors[severity], r
+		}
+	}
+	return color
+}
+
+// feishuMentionText 生成 @ 提醒文本，format 为 at 标签格式，参数依次为用户 ID 和显示名称；飞书不支持按手机号提醒
+func feishuMentionText(mentions Mentions, format string) string {
+	var tags []string
+	if mentions.All {
+		tags = append(tags, fmt.Sprintf(format, "all", "所有人"))
+	}
+	for _, user := range mentions.UserIDs {
+		tags = append(tags, fmt.Sprintf(format, user, ""))
+	}
+	return strings.Join(tags, " ")
+}
+
+// feishuEscape 转义 lark_md 中的特殊字符，避免标签值等内容被解析为格式标记
+func feishuEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "*", "&#42;", "~", "&#126;", "[", "&#91;", "]", "&#93;").Replace(s)
+}
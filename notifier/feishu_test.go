@@ -0,0 +1,128 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestFeishuSign(t *testing.T) {
+	n, err := NewFeishuNotifier("feishu", Config{WebhookURL: "https://open.feishu.cn/open-apis/bot/v2/hook/abc", Secret: "SEC1234567890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := n.(*FeishuNotifier).sign(FeishuMessage{MsgType: feishuMsgText}, time.Unix(1700000000, 0))
+	if msg.Timestamp != "1700000000" || msg.Sign != "V+FiVRDsBngZRtf146/IFigHPeCHmJ+UTGv9/zad7Nk=" {
+		t.Errorf("timestamp/sign = %s/%s", msg.Timestamp, msg.Sign)
+	}
+}
+
+func TestFeishuSendSignsBody(t *testing.T) {
+	server := newCaptureServer(t, `{"code":0,"msg":"success"}`)
+	n, err := NewFeishuNotifier("feishu", Config{WebhookURL: server.URL, Secret: "SEC1234567890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, testAlerts("HighCPU"), nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(requests[0].Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	timestamp, _ := body["timestamp"].(string)
+	sign, _ := body["sign"].(string)
+	if body["msg_type"] != feishuMsgText || timestamp == "" || sign == "" {
+		t.Fatalf("body = %s", requests[0].Body)
+	}
+	// 飞书按收到的秒级 timestamp 校验签名
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp %q is not in seconds: %v", timestamp, err)
+	}
+	if want := n.(*FeishuNotifier).sign(FeishuMessage{}, time.Unix(seconds, 0)).Sign; sign != want {
+		t.Errorf("sign = %s, want %s", sign, want)
+	}
+}
+
+func TestFeishuCardHeaderColor(t *testing.T) {
+	alert := func(status, severity string) template.Alert {
+		return template.Alert{Status: status, Labels: template.KV{"alertname": "HighCPU", "severity": severity}}
+	}
+	tests := []struct {
+		name   string
+		alerts template.Alerts
+		want   string
+	}{
+		{"emergency", template.Alerts{alert("firing", "emergency"), alert("firing", "warning")}, "red"},
+		{"critical", template.Alerts{alert("firing", "warning"), alert("firing", "critical")}, "orange"},
+		{"warning", template.Alerts{alert("firing", "warning")}, "yellow"},
+		{"info", template.Alerts{alert("firing", "info")}, "blue"},
+		{"unknown severity", template.Alerts{alert("firing", "page")}, "grey"},
+		// 已恢复的告警不参与颜色计算
+		{"resolved ignored", template.Alerts{alert("resolved", "emergency"), alert("firing", "info")}, "blue"},
+		{"all resolved", template.Alerts{alert("resolved", "critical")}, "green"},
+	}
+
+	n, err := NewFeishuNotifier("feishu", Config{WebhookURL: "https://open.feishu.cn", FeishuConfig: FeishuConfig{MsgType: "interactive"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := n.Format(template.Data{Status: "firing", Alerts: tt.alerts})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != 1 {
+				t.Fatalf("messages = %d, want 1", len(messages))
+			}
+			card := messages[0].Payload.(FeishuMessage).Card
+			if card == nil || card.Header.Template != tt.want {
+				t.Errorf("header = %+v, want template %s", card, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeishuVendorError(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		code     int
+	}{
+		{"code", `{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`, 19021},
+		{"legacy StatusCode", `{"StatusCode":9499,"StatusMessage":"Bad Request"}`, 9499},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newCaptureServer(t, tt.response)
+			maxRetries := 0
+			n, err := NewFeishuNotifier("feishu", Config{WebhookURL: server.URL, Delivery: DeliveryConfig{MaxRetries: &maxRetries}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, _, err = Dispatch(context.Background(), n, testAlerts("HighCPU"), nil)
+			var vendorErr *VendorError
+			if !errors.As(err, &vendorErr) || vendorErr.Code != tt.code {
+				t.Fatalf("err = %v, want VendorError %d", err, tt.code)
+			}
+			// 只有限流错误码可以重试
+			if IsRetryable(err) != feishuRateLimitCodes[tt.code] {
+				t.Errorf("retryable = %v for code %d", IsRetryable(err), tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// GenericConfig 通用 webhook 接收者配置，用于转发到工单、CMDB 等任意 HTTP 接口
+type GenericConfig struct {
+	// 请求方法：POST（默认）、PUT、PATCH
+	Method string `yaml:"method"`
+	// 请求头，值为 Go 模板，可通过 .Secrets 引用 secrets 中的密钥
+	Headers map[string]string `yaml:"headers"`
+	// 密钥，名称 -> 值，值以 env: 开头时读取环境变量，以 file: 开头时读取文件内容
+	Secrets map[string]string `yaml:"secrets"`
+	// 请求体模板（Go text/template），上下文为 template.Data，未配置时发送 Alertmanager 原始 JSON
+	Body string `yaml:"body"`
+}
+
+// GenericMessage 已渲染的请求体
+type GenericMessage struct {
+	Body string `json:"body"`
+}
+
+// GenericNotifier 通用 webhook 通知器，同一次推送的全部告警渲染为一个请求
+type GenericNotifier struct {
+	name       string
+	webhookURL string
+	method     string
+	header     http.Header
+	body       *texttemplate.Template
+	sender     *Sender
+	template   *utils.MessageTemplate
+}
+
+// genericHeaderData 请求头模板的上下文
+type genericHeaderData struct {
+	Secrets map[string]string
+}
+
+func init() {
+	Register("generic", NewGenericNotifier)
+}
+
+// NewGenericNotifier 创建通用 webhook 通知器，请求头在创建时渲染，密钥缺失或模板错误时返回错误
+func NewGenericNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+
+	method := strings.ToUpper(cfg.Method)
+	switch method {
+	case "":
+		method = http.MethodPost
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil, fmt.Errorf("接收者 %s 的 method %s 不受支持，可选: POST、PUT、PATCH", name, cfg.Method)
+	}
+
+	secrets := make(map[string]string, len(cfg.Secrets))
+	for key, value := range cfg.Secrets {
+		secret, err := resolveSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("接收者 %s 的密钥 %s 读取失败: %w", name, key, err)
+		}
+		secrets[key] = secret
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	headerFuncs := texttemplate.FuncMap{
+		// 生成 HTTP Basic 认证头的值
+		"basicAuth": func(username, password string) string {
+			return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+		},
+	}
+	for key, value := range cfg.Headers {
+		tmpl, err := texttemplate.New(key).Funcs(headerFuncs).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("接收者 %s 的请求头 %s 解析失败: %w", name, key, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, genericHeaderData{Secrets: secrets}); err != nil {
+			return nil, fmt.Errorf("接收者 %s 的请求头 %s 渲染失败: %w", name, key, err)
+		}
+		header.Set(key, buf.String())
+	}
+
+	var body *texttemplate.Template
+	if cfg.Body != "" {
+		var err error
+		body, err = texttemplate.New("body").Funcs(utils.TemplateFuncs(cfg.timeFormat())).Option("missingkey=zero").Parse(cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("接收者 %s 的 body 模板解析失败: %w", name, err)
+		}
+	}
+
+	return &GenericNotifier{
+		name:       name,
+		webhookURL: cfg.WebhookURL,
+		method:     method,
+		header:     header,
+		body:       body,
+		sender:     NewSender(name, cfg.Delivery, nil),
+		template:   cfg.MessageTemplate,
+	}, nil
+}
+
+func (g *GenericNotifier) Name() string {
+	return g.name
+}
+
+// MaxMessageSize 通用 webhook 不拆分消息
+func (g *GenericNotifier) MaxMessageSize() int {
+	return 0
+}
+
+// Format 渲染请求体：优先使用 body 模板，其次使用接收者的自定义消息模板，都未配置时为 Alertmanager 原始 JSON
+// Content-Type 为 JSON 时校验渲染结果，避免发送无法解析的请求体
+func (g *GenericNotifier) Format(data template.Data) ([]Message, error) {
+	var body string
+	if g.body != nil {
+		var buf bytes.Buffer
+		if err := g.body.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("[%s] 渲染请求体失败: %w", g.name, err)
+		}
+		body = buf.String()
+	} else if text, ok, err := g.template.Render(data); err != nil {
+		return nil, err
+	} else if ok {
+		body = text
+	} else {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] JSON编码失败: %w", g.name, err)
+		}
+		body = string(jsonData)
+	}
+
+	if strings.Contains(g.header.Get("Content-Type"), "json") && !json.Valid([]byte(body)) {
+		return nil, fmt.Errorf("[%s] 请求体不是合法的 JSON，可在模板中使用 toJSON 转义字段: %s", g.name, utils.Truncate(200, body))
+	}
+	return []Message{{Alerts: data.Alerts, Payload: GenericMessage{Body: body}}}, nil
+}
+
+func (g *GenericNotifier) Send(ctx context.Context, message interface{}) error {
+	msg, ok := message.(GenericMessage)
+	if !ok {
+		return &SendError{Err: fmt.Errorf("[%s] 不支持的消息类型 %T", g.name, message)}
+	}
+	return g.sender.SendRequest(ctx, g.method, g.webhookURL, g.header, []byte(msg.Body))
+}
+
+// TestConnection 通用 webhook 对接的多为工单等业务系统，测试消息可能产生脏数据，因此跳过连通性测试
+func (g *GenericNotifier) TestConnection() error {
+	log.Printf("[%s] 通用 webhook 不发送连通性测试消息", g.name)
+	return nil
+}
+
+// resolveSecret 读取密钥：env:NAME 读取环境变量，file:PATH 读取文件内容（去掉首尾空白），其他值原样使用
+func resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok || secret == "" {
+			return "", fmt.Errorf("环境变量 %s 未设置", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestGenericTemplatedHeadersAndBody(t *testing.T) {
+	server := newCaptureServer(t, `{"id":1}`)
+	t.Setenv("TICKET_TOKEN", "env-token")
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("file-password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := NewGenericNotifier("ticketing", Config{
+		WebhookURL: server.URL + "/api/v1/tickets",
+		GenericConfig: GenericConfig{
+			Method: "put",
+			Secrets: map[string]string{
+				"token":    "env:TICKET_TOKEN",
+				"password": "file:" + passwordFile,
+			},
+			Headers: map[string]string{
+				"X-Api-Token":   "Bearer {{ .Secrets.token }}",
+				"Authorization": `{{ basicAuth "alert" .Secrets.password }}`,
+			},
+			Body: `{"title": {{ printf "%s (%d)" .CommonLabels.alertname (len .Alerts) | toJSON }}, "status": {{ .Status | toJSON }}}`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := testAlerts("a", "b")
+	data.CommonLabels = template.KV{"alertname": `Disk "Full"`}
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	req := requests[0]
+	if req.Method != http.MethodPut || req.Path != "/api/v1/tickets" {
+		t.Errorf("request = %s %s", req.Method, req.Path)
+	}
+	if got := req.Header.Get("X-Api-Token"); got != "Bearer env-token" {
+		t.Errorf("X-Api-Token = %q", got)
+	}
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("alert:file-password"))
+	if got := req.Header.Get("Authorization"); got != wantAuth {
+		t.Errorf("Authorization = %q, want %q", got, wantAuth)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		t.Fatalf("body is not JSON: %v\n%s", err, req.Body)
+	}
+	if body["title"] != `Disk "Full" (2)` || body["status"] != "firing" {
+		t.Errorf("body = %v", body)
+	}
+}
+
+func TestGenericDefaultBodyIsAlertmanagerJSON(t *testing.T) {
+	n, err := NewGenericNotifier("raw", Config{WebhookURL: "http://127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := n.Format(testAlerts("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data template.Data
+	if err := json.Unmarshal([]byte(messages[0].Payload.(GenericMessage).Body), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Status != "firing" || len(data.Alerts) != 1 || data.Alerts[0].Labels["alertname"] != "a" {
+		t.Errorf("body = %+v", data)
+	}
+}
+
+func TestGenericRejectsInvalidJSONBody(t *testing.T) {
+	n, err := NewGenericNotifier("ticketing", Config{
+		WebhookURL:    "http://127.0.0.1",
+		GenericConfig: GenericConfig{Body: `{"title": "{{ .CommonLabels.alertname }}"}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := testAlerts("a")
+	data.CommonLabels = template.KV{"alertname": `say "hi"`}
+	if _, err := n.Format(data); err == nil || !strings.Contains(err.Error(), "toJSON") {
+		t.Errorf("Format = %v, want invalid JSON error", err)
+	}
+}
+
+func TestGenericMissingSecret(t *testing.T) {
+	_, err := NewGenericNotifier("ticketing", Config{
+		WebhookURL: "http://127.0.0.1",
+		GenericConfig: GenericConfig{
+			Headers: map[string]string{"X-Api-Token": "{{ .Secrets.token }}"},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error for header referencing an undefined secret")
+	}
+}
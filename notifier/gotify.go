@@ -0,0 +1,135 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// Gotify 消息优先级：Android 客户端 0 不通知，1~3 静默通知，4~7 通知并提示音，8 及以上弹出通知
+const (
+	gotifyPrioritySilent  = 0
+	gotifyPriorityLow     = 2
+	gotifyPriorityDefault = 5
+	gotifyPriorityHigh    = 8
+	gotifyPriorityMax     = 10
+)
+
+// GotifyMessage Gotify 创建消息请求体
+type GotifyMessage struct {
+	Title    string                 `json:"title,omitempty"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+// GotifyNotifier Gotify 手机推送通知器，Gotify 只能自建，必须配置 api_url
+type GotifyNotifier struct {
+	name       string
+	sendURL    string
+	header     http.Header
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+func init() {
+	Register("gotify", NewGotifyNotifier)
+}
+
+// NewGotifyNotifier 创建 Gotify 通知器，应用令牌通过 X-Gotify-Key 请求头传递，不出现在请求地址中
+func NewGotifyNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.APIURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的 api_url 未配置", name)
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("接收者 %s 的 token 未配置", name)
+	}
+
+	token, err := resolveSecret(cfg.Token)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 token 读取失败: %w", name, err)
+	}
+
+	n := &GotifyNotifier{
+		name:       name,
+		sendURL:    strings.TrimRight(cfg.APIURL, "/") + "/message",
+		header:     http.Header{"X-Gotify-Key": {token}},
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	// Gotify 以 HTTP 状态码返回错误
+	n.sender = NewSender(name, cfg.Delivery, nil)
+	return n, nil
+}
+
+func (g *GotifyNotifier) Name() string {
+	return g.name
+}
+
+func (g *GotifyNotifier) MaxMessageSize() int {
+	return pushMaxLength
+}
+
+// Format 标题为告警分组和数量，正文超过长度限制时分批推送，优先级取每批中的最高告警级别
+func (g *GotifyNotifier) Format(data template.Data) ([]Message, error) {
+	format := messageFormatter(g.template, formatPushText, g.timeFormat)
+	batches := utils.SplitAlerts(data, g.MaxMessageSize(), format.Text)
+
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		message := GotifyMessage{
+			Title:    pushTitle(batch),
+			Message:  format.Text(batch),
+			Priority: gotifyPriority(pushSeverity(batch)),
+		}
+		if click := pushClickURL(batch); click != "" {
+			message.Extras = map[string]interface{}{
+				"client::notification": map[string]interface{}{"click": map[string]string{"url": click}},
+			}
+		}
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: message})
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (g *GotifyNotifier) Send(ctx context.Context, message interface{}) error {
+	return g.sender.Retry(ctx, func(ctx context.Context) error {
+		return g.sender.postHeader(ctx, g.sendURL, g.header, message)
+	})
+}
+
+// TestConnection 以优先级0发送测试消息，客户端不弹出通知
+func (g *GotifyNotifier) TestConnection() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return g.sender.postHeader(ctx, g.sendURL, g.header, GotifyMessage{
+		Title:    "Gotify连通性测试",
+		Message:  "Gotify连通性测试",
+		Priority: gotifyPrioritySilent,
+	})
+}
+
+// gotifyPriority 将 MapSeverity 的级别映射为 Gotify 优先级，P0 为最高优先级，已恢复为低优先级
+func gotifyPriority(severity string) int {
+	switch severity {
+	case "P0":
+		return gotifyPriorityMax
+	case "P1":
+		return gotifyPriorityHigh
+	case "P2":
+		return gotifyPriorityDefault
+	case "P3", "":
+		return gotifyPriorityLow
+	default:
+		return gotifyPriorityDefault
+	}
+}
@@ -0,0 +1,126 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+)
+
+// kafkaIDHeader 消息头中的告警消息 ID，用于消费端去重
+const kafkaIDHeader = "alert-id"
+
+// kafkaPublisher 将告警写入 Kafka topic，消息 key 为告警指纹，同一告警的消息进入同一分区并保持顺序
+type kafkaPublisher struct {
+	name    string
+	topic   string
+	options []kgo.Opt
+
+	mu     sync.Mutex
+	client *kgo.Client
+}
+
+func init() {
+	RegisterBus("kafka", newKafkaPublisher)
+}
+
+// newKafkaPublisher 创建 Kafka 生产者，配置了 username 时使用 SASL/PLAIN 鉴权
+// 生产者默认开启幂等写入并等待全部 ISR 副本确认
+func newKafkaPublisher(name string, cfg Config) (Publisher, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("接收者 %s 的 brokers 未配置", name)
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("接收者 %s 的 topic 未配置", name)
+	}
+
+	options := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.DefaultProduceTopic(cfg.Topic),
+		kgo.ClientID("alert-webhook"),
+	}
+	if cfg.Username != "" {
+		password, err := busPassword(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, kgo.SASL(plain.Auth{User: cfg.Username, Pass: password}.AsMechanism()))
+	}
+	if tlsConfig := cfg.tlsConfig(); tlsConfig != nil {
+		options = append(options, kgo.DialTLSConfig(tlsConfig))
+	}
+
+	return &kafkaPublisher{
+		name:    name,
+		topic:   cfg.Topic,
+		options: options,
+	}, nil
+}
+
+// Publish 同步写入一批消息，全部消息被 broker 确认后返回
+func (p *kafkaPublisher) Publish(ctx context.Context, messages []BusMessage) error {
+	client, err := p.connect()
+	if err != nil {
+		return busError(p.name, false, err)
+	}
+
+	records := make([]*kgo.Record, 0, len(messages))
+	for _, message := range messages {
+		records = append(records, &kgo.Record{
+			Key:     []byte(message.Key),
+			Value:   message.Value,
+			Headers: []kgo.RecordHeader{{Key: kafkaIDHeader, Value: []byte(message.ID)}},
+		})
+	}
+	if err := client.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		return busError(p.name, kafkaRetryable(err), err)
+	}
+	return nil
+}
+
+func (p *kafkaPublisher) Ping(ctx context.Context) error {
+	client, err := p.connect()
+	if err != nil {
+		return err
+	}
+	return client.Ping(ctx)
+}
+
+func (p *kafkaPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+	return nil
+}
+
+// connect 返回生产者客户端，客户端在首次发布时才连接 broker，之后自动重连
+func (p *kafkaPublisher) connect() (*kgo.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client != nil {
+		return p.client, nil
+	}
+	client, err := kgo.NewClient(p.options...)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Kafka 客户端失败: %w", err)
+	}
+	p.client = client
+	return client, nil
+}
+
+// kafkaRetryable broker 返回的错误按 Kafka 协议的可重试标记判断（如消息过大、无权限不重试），网络错误和超时重试
+func kafkaRetryable(err error) bool {
+	var kafkaErr *kerr.Error
+	if errors.As(err, &kafkaErr) {
+		return kafkaErr.Retriable
+	}
+	return true
+}
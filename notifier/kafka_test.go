@@ -0,0 +1,146 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// runKafkaCluster 启动进程内 Kafka 模拟集群并预建 alerts topic
+func runKafkaCluster(t *testing.T, opts ...kfake.Opt) *kfake.Cluster {
+	t.Helper()
+	cluster, err := kfake.NewCluster(append([]kfake.Opt{kfake.NumBrokers(1), kfake.SeedTopics(1, "alerts")}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+func newTestKafka(t *testing.T, cluster *kfake.Cluster, username, password string) Notifier {
+	t.Helper()
+	maxRetries := 0
+	n, err := New("alert-kafka", Config{
+		Type:     "kafka",
+		Topic:    "alerts",
+		Delivery: DeliveryConfig{MaxRetries: &maxRetries},
+		BusConfig: BusConfig{
+			Brokers:  cluster.ListenAddrs(),
+			Username: username,
+			Password: password,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { n.(*BusNotifier).Close() })
+	return n
+}
+
+func TestKafkaPublish(t *testing.T) {
+	cluster := runKafkaCluster(t)
+	n := newTestKafka(t, cluster, "", "")
+
+	data := testAlerts("HighCPU", "DiskFull")
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	consumer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.ConsumeTopics("alerts"), kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	var records []*kgo.Record
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for len(records) < len(data.Alerts) {
+		fetches := consumer.PollFetches(ctx)
+		if err := ctx.Err(); err != nil {
+			t.Fatalf("records = %d, want %d: %v", len(records), len(data.Alerts), err)
+		}
+		records = append(records, fetches.Records()...)
+	}
+
+	for i, record := range records {
+		alert := data.Alerts[i]
+		fingerprint := utils.AlertFingerprint(alert)
+		if record.Topic != "alerts" || string(record.Key) != fingerprint {
+			t.Errorf("record %d topic = %q key = %q, want alerts/%s", i, record.Topic, record.Key, fingerprint)
+		}
+		if len(record.Headers) != 1 || record.Headers[0].Key != kafkaIDHeader || !strings.HasPrefix(string(record.Headers[0].Value), fingerprint+"-firing-") {
+			t.Errorf("record %d headers = %+v", i, record.Headers)
+		}
+		var busAlert BusAlert
+		if err := json.Unmarshal(record.Value, &busAlert); err != nil {
+			t.Fatal(err)
+		}
+		if busAlert.Fingerprint != fingerprint || busAlert.Receiver != "alert-kafka" || busAlert.Labels["alertname"] != alert.Labels["alertname"] {
+			t.Errorf("record %d alert = %+v", i, busAlert)
+		}
+	}
+}
+
+func TestKafkaSASLPlain(t *testing.T) {
+	cluster := runKafkaCluster(t, kfake.EnableSASL(), kfake.Superuser("PLAIN", "alert", "s3cret"))
+	t.Setenv("KAFKA_PASSWORD", "s3cret")
+
+	n := newTestKafka(t, cluster, "alert", "env:KAFKA_PASSWORD")
+	if _, _, _, err := Dispatch(context.Background(), n, testAlerts("a"), nil); err != nil {
+		t.Fatalf("Dispatch with valid credentials: %v", err)
+	}
+
+	// franz-go 在鉴权失败后持续重连，错误只能在超时后返回
+	n = newTestKafka(t, cluster, "alert", "wrong")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, _, err := Dispatch(ctx, n, testAlerts("a"), nil); err == nil {
+		t.Fatal("Dispatch with wrong password succeeded")
+	}
+}
+
+func TestKafkaBrokerErrorNotRetryable(t *testing.T) {
+	cluster := runKafkaCluster(t)
+	// 模拟 broker 以 MESSAGE_TOO_LARGE 拒绝写入
+	cluster.ControlKey(int16(kmsg.Produce), func(req kmsg.Request) (kmsg.Response, error, bool) {
+		produce := req.(*kmsg.ProduceRequest)
+		resp := produce.ResponseKind().(*kmsg.ProduceResponse)
+		for _, topic := range produce.Topics {
+			respTopic := kmsg.NewProduceResponseTopic()
+			respTopic.Topic = topic.Topic
+			respTopic.TopicID = topic.TopicID
+			for _, partition := range topic.Partitions {
+				respPartition := kmsg.NewProduceResponseTopicPartition()
+				respPartition.Partition = partition.Partition
+				respPartition.ErrorCode = kerr.MessageTooLarge.Code
+				respTopic.Partitions = append(respTopic.Partitions, respPartition)
+			}
+			resp.Topics = append(resp.Topics, respTopic)
+		}
+		return resp, nil, true
+	})
+	n := newTestKafka(t, cluster, "", "")
+
+	_, _, _, err := Dispatch(context.Background(), n, testAlerts("a"), nil)
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("err = %v, want non-retryable MESSAGE_TOO_LARGE error", err)
+	}
+}
+
+func TestKafkaRequiresBrokersAndTopic(t *testing.T) {
+	if _, err := New("alert-kafka", Config{Type: "kafka", Topic: "alerts"}); err == nil {
+		t.Error("expected error for missing brokers")
+	}
+	if _, err := New("alert-kafka", Config{Type: "kafka", BusConfig: BusConfig{Brokers: []string{"127.0.0.1:9092"}}}); err == nil {
+		t.Error("expected error for missing topic")
+	}
+}
@@ -0,0 +1,125 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"fmt"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// MentionTarget @ 提醒对象
+type MentionTarget struct {
+	// 手机号，用于企业微信和钉钉
+	Mobiles []string `yaml:"mobiles"`
+	// 用户 ID：企业微信 userid、钉钉 userId、飞书 open_id 或 user_id
+	UserIDs []string `yaml:"user_ids"`
+	// @所有人
+	All bool `yaml:"all"`
+}
+
+// MentionRule @ 提醒规则，告警中的告警满足 matchers 时提醒配置的对象；
+// 配置了 label 时按告警该标签的值在 users 中查找提醒对象，如 owner 标签对应的负责人
+type MentionRule struct {
+	// 标签匹配规则，语法与路由的 matchers 相同，例如 severity=~"critical|emergency"，未配置时匹配全部告警
+	Matchers      []string `yaml:"matchers"`
+	MentionTarget `yaml:",inline"`
+	// 按标签值选择提醒对象的标签名
+	Label string `yaml:"label"`
+	// 标签值 -> 提醒对象
+	Users map[string]MentionTarget `yaml:"users"`
+
+	matchers []*labels.Matcher
+}
+
+// Mentions 一批告警需要提醒的对象，已去重
+type Mentions struct {
+	Mobiles []string
+	UserIDs []string
+	All     bool
+}
+
+// Empty 判断是否没有需要提醒的对象
+func (m Mentions) Empty() bool {
+	return !m.All && len(m.Mobiles) == 0 && len(m.UserIDs) == 0
+}
+
+// mentioner 按规则计算告警需要提醒的对象
+type mentioner struct {
+	rules []MentionRule
+}
+
+// newMentioner 解析提醒规则中的匹配规则，未配置规则时返回 nil
+func newMentioner(name string, rules []MentionRule) (*mentioner, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	compiled := make([]MentionRule, 0, len(rules))
+	for i, rule := range rules {
+		for _, s := range rule.Matchers {
+			m, err := labels.ParseMatcher(s)
+			if err != nil {
+				return nil, fmt.Errorf("接收者 %s 的第 %d 条 mentions 规则匹配条件 %q 解析失败: %w", name, i+1, s, err)
+			}
+			rule.matchers = append(rule.matchers, m)
+		}
+		if rule.Label == "" && len(rule.Users) > 0 {
+			return nil, fmt.Errorf("接收者 %s 的第 %d 条 mentions 规则配置了 users 但未配置 label", name, i+1)
+		}
+		compiled = append(compiled, rule)
+	}
+	return &mentioner{rules: compiled}, nil
+}
+
+// Mentions 返回一批告警需要提醒的对象，只有告警中的告警会触发提醒；m 为 nil 时返回空
+func (m *mentioner) Mentions(batch template.Data) Mentions {
+	var result Mentions
+	if m == nil {
+		return result
+	}
+
+	seenMobiles := make(map[string]bool)
+	seenUsers := make(map[string]bool)
+	add := func(target MentionTarget) {
+		result.All = result.All || target.All
+		for _, mobile := range target.Mobiles {
+			if !seenMobiles[mobile] {
+				seenMobiles[mobile] = true
+				result.Mobiles = append(result.Mobiles, mobile)
+			}
+		}
+		for _, user := range target.UserIDs {
+			if !seenUsers[user] {
+				seenUsers[user] = true
+				result.UserIDs = append(result.UserIDs, user)
+			}
+		}
+	}
+
+	firing, _ := utils.SplitByStatus(batch)
+	for _, alert := range firing {
+		for _, rule := range m.rules {
+			if !rule.matches(alert.Labels) {
+				continue
+			}
+			add(rule.MentionTarget)
+			if rule.Label != "" {
+				if target, ok := rule.Users[alert.Labels[rule.Label]]; ok {
+					add(target)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// matches 判断告警标签是否满足规则的全部匹配条件，未配置的标签按空字符串处理
+func (r MentionRule) matches(alertLabels template.KV) bool {
+	for _, m := range r.matchers {
+		if !m.Matches(alertLabels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
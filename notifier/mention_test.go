@@ -0,0 +1,240 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// testMentionRules P0/P1 告警提醒值班人员，并按 owner 标签提醒负责人
+var testMentionRules = []MentionRule{
+	{
+		Matchers:      []string{`severity=~"emergency|critical"`},
+		MentionTarget: MentionTarget{Mobiles: []string{"13800000000"}, UserIDs: []string{"oncall"}},
+	},
+	{
+		Label: "owner",
+		Users: map[string]MentionTarget{
+			"alice": {Mobiles: []string{"13900000000"}, UserIDs: []string{"alice"}},
+			"bob":   {UserIDs: []string{"bob"}, All: true},
+		},
+	},
+}
+
+func mentionAlert(status, name, severity, owner string) template.Alert {
+	return template.Alert{
+		Status: status,
+		Labels: template.KV{"alertname": name, "severity": severity, "owner": owner},
+	}
+}
+
+func TestMentionerResolvesTargets(t *testing.T) {
+	m, err := newMentioner("ops", testMentionRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		alerts template.Alerts
+		want   Mentions
+	}{
+		{"severity rule", template.Alerts{mentionAlert("firing", "HighCPU", "critical", "")},
+			Mentions{Mobiles: []string{"13800000000"}, UserIDs: []string{"oncall"}}},
+		{"owner label", template.Alerts{mentionAlert("firing", "HighCPU", "warning", "alice")},
+			Mentions{Mobiles: []string{"13900000000"}, UserIDs: []string{"alice"}}},
+		// 多条规则命中时合并去重
+		{"merged", template.Alerts{mentionAlert("firing", "A", "critical", "alice"), mentionAlert("firing", "B", "emergency", "bob")},
+			Mentions{Mobiles: []string{"13800000000", "13900000000"}, UserIDs: []string{"oncall", "alice", "bob"}, All: true}},
+		{"resolved not mentioned", template.Alerts{mentionAlert("resolved", "HighCPU", "critical", "alice")}, Mentions{}},
+		{"no match", template.Alerts{mentionAlert("firing", "HighCPU", "warning", "carol")}, Mentions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Mentions(template.Data{Status: "firing", Alerts: tt.alerts})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionerConfigErrors(t *testing.T) {
+	if _, err := newMentioner("ops", []MentionRule{{Matchers: []string{"severity=~("}}}); err == nil {
+		t.Error("expected error for invalid matcher")
+	}
+	if _, err := newMentioner("ops", []MentionRule{{Users: map[string]MentionTarget{"alice": {}}}}); err == nil {
+		t.Error("expected error for users without label")
+	}
+}
+
+func TestDingTalkMentions(t *testing.T) {
+	n, err := NewDingTalkNotifier("dingtalk", Config{WebhookURL: "https://oapi.dingtalk.com/robot/send", Mentions: testMentionRules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := n.Format(template.Data{Status: "firing", Alerts: template.Alerts{mentionAlert("firing", "HighCPU", "critical", "bob")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := messages[0].Payload.(DingTalkMessage)
+	want := &DingTalkAt{AtMobiles: []string{"13800000000"}, AtUserIds: []string{"oncall", "bob"}, IsAtAll: true}
+	if !reflect.DeepEqual(msg.At, want) {
+		t.Errorf("at = %+v, want %+v", msg.At, want)
+	}
+	// 钉钉只高亮文本中出现的手机号和 userId
+	if !strings.HasSuffix(msg.Markdown.Text, "\n\n@13800000000 @oncall @bob @所有人") {
+		t.Errorf("text = %q", msg.Markdown.Text)
+	}
+}
+
+func TestDingTalkSplitReservesMentionText(t *testing.T) {
+	// 约 4KB 的 @ 文本，拆分时必须计入
+	mobiles := make([]string, 300)
+	for i := range mobiles {
+		mobiles[i] = fmt.Sprintf("138%08d", i)
+	}
+	n, err := NewDingTalkNotifier("dingtalk", Config{
+		WebhookURL: "https://oapi.dingtalk.com/robot/send",
+		Mentions:   []MentionRule{{MentionTarget: MentionTarget{Mobiles: mobiles}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := template.Data{Status: "firing"}
+	for i := 0; i < 40; i++ {
+		alert := mentionAlert("firing", fmt.Sprintf("Alert%02d", i), "warning", "")
+		alert.Annotations = template.KV{"description": strings.Repeat("x", 1000)}
+		data.Alerts = append(data.Alerts, alert)
+	}
+	messages, err := n.Format(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) < 2 {
+		t.Fatalf("messages = %d, want alerts split into several messages", len(messages))
+	}
+	for i, message := range messages {
+		msg := message.Payload.(DingTalkMessage)
+		if len(msg.Markdown.Text) > dingTalkMaxLength {
+			t.Errorf("message %d text = %d bytes, exceeds %d", i, len(msg.Markdown.Text), dingTalkMaxLength)
+		}
+		if !strings.HasSuffix(msg.Markdown.Text, "@138"+fmt.Sprintf("%08d", 299)) {
+			t.Errorf("message %d missing mention text", i)
+		}
+	}
+}
+
+func TestFeishuMentions(t *testing.T) {
+	data := template.Data{Status: "firing", Alerts: template.Alerts{mentionAlert("firing", "HighCPU", "critical", "bob")}}
+
+	n, err := NewFeishuNotifier("feishu", Config{WebhookURL: "https://open.feishu.cn", Mentions: testMentionRules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := n.Format(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 飞书不支持按手机号提醒
+	text := messages[0].Payload.(FeishuMessage).Content.Text
+	if !strings.HasSuffix(text, "\n"+`<at user_id="all">所有人</at> <at user_id="oncall"></at> <at user_id="bob"></at>`) || strings.Contains(text, "13800000000") {
+		t.Errorf("text = %q", text)
+	}
+
+	n, err = NewFeishuNotifier("feishu", Config{WebhookURL: "https://open.feishu.cn", Mentions: testMentionRules, FeishuConfig: FeishuConfig{MsgType: "interactive"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err = n.Format(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elements := messages[0].Payload.(FeishuMessage).Card.Elements
+	last := elements[len(elements)-1]
+	if last.Text == nil || last.Text.Content != "<at id=all>所有人</at> <at id=oncall></at> <at id=bob></at>" {
+		t.Errorf("last card element = %+v", last)
+	}
+}
+
+func TestWeChatMentionFollowsEachBatch(t *testing.T) {
+	n, err := NewWeChatNotifier("wechat", Config{WebhookURL: "https://qyapi.weixin.qq.com", Mentions: testMentionRules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一批都是 warning，不需要提醒；第二批为 critical，提醒值班人员
+	messages, err := n.Format(largeWeChatAlerts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, message := range messages {
+		types = append(types, message.Payload.(WeChatMessage).MsgType)
+	}
+	if strings.Join(types, ",") != "markdown,markdown,text" {
+		t.Fatalf("message types = %v, want mention text after the critical batch only", types)
+	}
+	mention := messages[2]
+	text := mention.Payload.(WeChatMessage).Text
+	if !reflect.DeepEqual(text.MentionedList, []string{"oncall"}) || !reflect.DeepEqual(text.MentionedMobileList, []string{"13800000000"}) {
+		t.Errorf("mention = %+v", text)
+	}
+	if !reflect.DeepEqual(mention.Alerts, messages[1].Alerts) {
+		t.Errorf("mention alerts = %v, want alerts of its batch", mention.Alerts)
+	}
+}
+
+func TestWeChatMentionFailureRequeuesOnlyItsBatch(t *testing.T) {
+	// 文本提醒消息返回不可重试的错误码，markdown 消息发送成功
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var msg WeChatMessage
+		json.Unmarshal(body, &msg)
+		if msg.MsgType == "text" {
+			io.WriteString(w, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+			return
+		}
+		io.WriteString(w, `{"errcode":0,"errmsg":"ok"}`)
+	}))
+	defer server.Close()
+
+	maxRetries := 0
+	n, err := NewWeChatNotifier("wechat", Config{WebhookURL: server.URL, Mentions: testMentionRules, Delivery: DeliveryConfig{MaxRetries: &maxRetries}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := largeWeChatAlerts()
+	delivered, sent, total, err := Dispatch(context.Background(), n, data, nil)
+	if err == nil || sent != 2 || total != 3 {
+		t.Fatalf("sent/total = %d/%d, err = %v", sent, total, err)
+	}
+
+	// 只有第二批（critical）的告警需要重新投递
+	want := []string{utils.AlertFingerprint(data.Alerts[0]), utils.AlertFingerprint(data.Alerts[1])}
+	if !reflect.DeepEqual(delivered, want) {
+		t.Errorf("delivered = %v, want fingerprints of the warning batch", delivered)
+	}
+}
+
+// largeWeChatAlerts 两个 warning 告警和两个 critical 告警，每两个告警一批
+func largeWeChatAlerts() template.Data {
+	data := template.Data{Status: "firing"}
+	for i, severity := range []string{"warning", "warning", "critical", "critical"} {
+		alert := mentionAlert("firing", fmt.Sprintf("Alert%d", i), severity, "")
+		alert.Annotations = template.KV{"description": strings.Repeat("x", 1500)}
+		data.Alerts = append(data.Alerts, alert)
+	}
+	return data
+}
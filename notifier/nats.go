@@ -0,0 +1,137 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsMsgIDHeader 被 JetStream stream 捕获时，按此消息头在去重窗口内丢弃重复消息
+const natsMsgIDHeader = "Nats-Msg-Id"
+
+// natsPublisher 将告警发布到 subject <topic>.<指纹>，订阅 <topic>.> 接收全部告警，
+// 订阅 <topic>.<指纹> 只接收同一告警的状态变化
+type natsPublisher struct {
+	name    string
+	subject string
+	servers string
+	options []nats.Option
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+func init() {
+	RegisterBus("nats", newNATSPublisher)
+}
+
+// newNATSPublisher 创建 NATS 客户端，brokers 未配置时连接 nats://127.0.0.1:4222
+func newNATSPublisher(name string, cfg Config) (Publisher, error) {
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("接收者 %s 的 topic 未配置", name)
+	}
+	if strings.ContainsAny(cfg.Topic, " \t*>") {
+		return nil, fmt.Errorf("接收者 %s 的 topic %s 不能包含空白字符和通配符", name, cfg.Topic)
+	}
+
+	servers := nats.DefaultURL
+	if len(cfg.Brokers) > 0 {
+		servers = strings.Join(cfg.Brokers, ",")
+	}
+
+	options := []nats.Option{
+		nats.Name("alert-webhook/" + name),
+		nats.Timeout(5 * time.Second),
+		// 连接断开后持续重连，重连期间发布的消息在客户端缓存，Flush 超时后重试
+		nats.MaxReconnects(-1),
+	}
+	if cfg.Token != "" {
+		token, err := resolveSecret(cfg.Token)
+		if err != nil {
+			return nil, fmt.Errorf("接收者 %s 的 token 读取失败: %w", name, err)
+		}
+		options = append(options, nats.Token(token))
+	}
+	if cfg.Username != "" {
+		password, err := busPassword(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, nats.UserInfo(cfg.Username, password))
+	}
+	if tlsConfig := cfg.tlsConfig(); tlsConfig != nil {
+		options = append(options, nats.Secure(tlsConfig))
+	}
+
+	return &natsPublisher{
+		name:    name,
+		subject: strings.TrimSuffix(cfg.Topic, "."),
+		servers: servers,
+		options: options,
+	}, nil
+}
+
+// Publish 发布一批消息后 Flush，服务端确认收到全部消息后返回
+func (p *natsPublisher) Publish(ctx context.Context, messages []BusMessage) error {
+	conn, err := p.connect()
+	if err != nil {
+		return busError(p.name, true, err)
+	}
+
+	for _, message := range messages {
+		msg := nats.NewMsg(p.subject + "." + message.Key)
+		msg.Data = message.Value
+		msg.Header.Set(natsMsgIDHeader, message.ID)
+		if err := conn.PublishMsg(msg); err != nil {
+			return busError(p.name, natsRetryable(err), err)
+		}
+	}
+	if err := conn.FlushWithContext(ctx); err != nil {
+		return busError(p.name, true, err)
+	}
+	return nil
+}
+
+func (p *natsPublisher) Ping(ctx context.Context) error {
+	conn, err := p.connect()
+	if err != nil {
+		return err
+	}
+	return conn.FlushWithContext(ctx)
+}
+
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	return nil
+}
+
+// connect 返回已建立的连接，首次调用或连接已关闭时重新连接
+func (p *natsPublisher) connect() (*nats.Conn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil && !p.conn.IsClosed() {
+		return p.conn, nil
+	}
+	conn, err := nats.Connect(p.servers, p.options...)
+	if err != nil {
+		return nil, fmt.Errorf("连接 NATS 服务 %s 失败: %w", p.servers, err)
+	}
+	p.conn = conn
+	return conn, nil
+}
+
+// natsRetryable 消息超过服务端大小限制等客户端错误不重试
+func natsRetryable(err error) bool {
+	return !errors.Is(err, nats.ErrMaxPayload) && !errors.Is(err, nats.ErrBadSubject)
+}
@@ -0,0 +1,132 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// runNATSServer 启动进程内 NATS 服务，configure 可修改服务端配置
+func runNATSServer(t *testing.T, configure func(*server.Options)) *server.Server {
+	t.Helper()
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	if configure != nil {
+		configure(&opts)
+	}
+	s := natstest.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func newTestNATS(t *testing.T, cfg Config) Notifier {
+	t.Helper()
+	maxRetries := 0
+	cfg.Type = "nats"
+	cfg.Delivery = DeliveryConfig{MaxRetries: &maxRetries}
+	n, err := New("alert-bus", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { n.(*BusNotifier).Close() })
+	return n
+}
+
+func TestNATSPublish(t *testing.T) {
+	s := runNATSServer(t, nil)
+	sub, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	received := make(chan *nats.Msg, 10)
+	if _, err := sub.ChanSubscribe("alerts.prod.>", received); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// topic 末尾的点会被去掉
+	n := newTestNATS(t, Config{Topic: "alerts.prod.", BusConfig: BusConfig{Brokers: []string{s.ClientURL()}}})
+	data := testAlerts("HighCPU", "DiskFull")
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	for _, alert := range data.Alerts {
+		var msg *nats.Msg
+		select {
+		case msg = <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for message")
+		}
+		fingerprint := utils.AlertFingerprint(alert)
+		if msg.Subject != "alerts.prod."+fingerprint {
+			t.Errorf("subject = %q, want alerts.prod.%s", msg.Subject, fingerprint)
+		}
+		if id := msg.Header.Get(natsMsgIDHeader); !strings.HasPrefix(id, fingerprint+"-firing-") {
+			t.Errorf("%s = %q", natsMsgIDHeader, id)
+		}
+		var busAlert BusAlert
+		if err := json.Unmarshal(msg.Data, &busAlert); err != nil {
+			t.Fatal(err)
+		}
+		if busAlert.Fingerprint != fingerprint || busAlert.Labels["alertname"] != alert.Labels["alertname"] || busAlert.Receiver != "alert-bus" {
+			t.Errorf("alert = %+v", busAlert)
+		}
+	}
+}
+
+func TestNATSTokenAuth(t *testing.T) {
+	s := runNATSServer(t, func(opts *server.Options) { opts.Authorization = "s3cret" })
+	t.Setenv("NATS_TOKEN", "s3cret")
+
+	n := newTestNATS(t, Config{Topic: "alerts", Token: "env:NATS_TOKEN", BusConfig: BusConfig{Brokers: []string{s.ClientURL()}}})
+	if _, _, _, err := Dispatch(context.Background(), n, testAlerts("a"), nil); err != nil {
+		t.Fatalf("Dispatch with token: %v", err)
+	}
+
+	n = newTestNATS(t, Config{Topic: "alerts", Token: "wrong", BusConfig: BusConfig{Brokers: []string{s.ClientURL()}}})
+	if _, _, _, err := Dispatch(context.Background(), n, testAlerts("a"), nil); err == nil {
+		t.Fatal("Dispatch with wrong token succeeded")
+	}
+}
+
+func TestNATSMaxPayloadNotRetryable(t *testing.T) {
+	s := runNATSServer(t, func(opts *server.Options) { opts.MaxPayload = 256 })
+	n := newTestNATS(t, Config{Topic: "alerts", BusConfig: BusConfig{Brokers: []string{s.ClientURL()}}})
+
+	data := testAlerts("a")
+	data.Alerts[0].Annotations = template.KV{"description": strings.Repeat("x", 512)}
+	_, _, _, err := Dispatch(context.Background(), n, data, nil)
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("err = %v, want non-retryable max payload error", err)
+	}
+}
+
+func TestNATSUnavailableRetryable(t *testing.T) {
+	s := runNATSServer(t, nil)
+	url := s.ClientURL()
+	s.Shutdown()
+
+	n := newTestNATS(t, Config{Topic: "alerts", BusConfig: BusConfig{Brokers: []string{url}}})
+	_, _, _, err := Dispatch(context.Background(), n, testAlerts("a"), nil)
+	if err == nil || !IsRetryable(err) {
+		t.Fatalf("err = %v, want retryable connection error", err)
+	}
+}
+
+func TestNATSRejectsWildcardTopic(t *testing.T) {
+	if _, err := New("alert-bus", Config{Type: "nats", Topic: "alerts.*"}); err == nil {
+		t.Error("expected error for wildcard topic")
+	}
+}
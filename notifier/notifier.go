@@ -0,0 +1,397 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// Notifier 告警通知渠道，每种客户端类型（企业微信、钉钉、飞书等）实现一次
+type Notifier interface {
+	// Name 返回通知器名称，用于日志和失败统计
+	Name() string
+	// Format 将告警数据格式化为渠道消息，超过长度限制时拆分为多条
+	Format(data template.Data) ([]Message, error)
+	// Send 发送一条已格式化消息的 Payload，ctx 取消时停止重试
+	Send(ctx context.Context, message interface{}) error
+	// TestConnection 发送连通性测试消息
+	TestConnection() error
+	// MaxMessageSize 返回单条消息的最大字节数，0 表示不限制
+	MaxMessageSize() int
+}
+
+// Message 一条已格式化的渠道消息及其包含的告警
+// 重新投递时按告警指纹跳过已发送的告警，不依赖消息的拆分方式
+type Message struct {
+	// 消息中包含的告警，消息发送成功后这些告警视为已发送
+	Alerts []template.Alert
+	// 传给 Send 的渠道消息
+	Payload interface{}
+}
+
+// Config 单个通知器（接收者）的配置
+type Config struct {
+	// 通知器类型（如 wechat、dingtalk、feishu、slack、teams、discord、mattermost、rocketchat、telegram、email、generic、wechat_app、ntfy、gotify、bark、sms、voice、nats、kafka、redis_stream），为空时使用接收者名称作为类型
+	Type       string `yaml:"type"`
+	WebhookURL string `yaml:"webhook_url"`
+	// 平台 API 地址，用于私有部署、代理或本地模拟服务，未配置时使用官方地址
+	APIURL string `yaml:"api_url"`
+	// 请求签名密钥（机器人加签、短信网关签名），支持 env: 和 file: 前缀，未配置时不签名
+	Secret string `yaml:"secret"`
+	// 访问令牌（ntfy、Gotify、NATS），支持 env: 和 file: 前缀
+	Token string `yaml:"token"`
+	// 主题：ntfy 主题、NATS subject、Kafka topic 或 Redis stream
+	Topic string `yaml:"topic"`
+	// 告警中的告警按规则 @ 提醒相关人员，支持企业微信、钉钉、飞书
+	Mentions []MentionRule `yaml:"mentions"`
+	// 发送超时与重试配置，未配置的字段使用全局 delivery 配置
+	Delivery DeliveryConfig `yaml:"delivery"`
+	// 自定义消息模板，未配置时使用内置格式
+	Template TemplateConfig `yaml:"template"`
+	// 是否发送恢复通知，默认发送；为 false 时丢弃发往该接收者的已恢复告警
+	SendResolved *bool `yaml:"send_resolved"`
+	// 消息中时间的时区（IANA 名称）和 Go 时间格式，未配置时使用全局配置
+	Timezone   string `yaml:"timezone"`
+	TimeFormat string `yaml:"time_format"`
+
+	// 各渠道特有的配置，字段直接写在接收者下
+	TelegramConfig  `yaml:",inline"`
+	EmailConfig     `yaml:",inline"`
+	GenericConfig   `yaml:",inline"`
+	FeishuConfig    `yaml:",inline"`
+	WeChatAppConfig `yaml:",inline"`
+	PushConfig      `yaml:",inline"`
+	SMSConfig       `yaml:",inline"`
+	BusConfig       `yaml:",inline"`
+
+	// 加载配置时根据 Template 解析出的模板
+	MessageTemplate *utils.MessageTemplate `yaml:"-"`
+	// 加载配置时根据 Timezone 和 TimeFormat 校验后的时间格式
+	Time utils.TimeFormat `yaml:"-"`
+}
+
+// ShouldSendResolved 判断接收者是否接收恢复通知，未配置时默认接收
+func (c Config) ShouldSendResolved() bool {
+	return c.SendResolved == nil || *c.SendResolved
+}
+
+// timeFormat 返回接收者使用的时间格式，未经配置加载校验时使用默认值
+func (c Config) timeFormat() utils.TimeFormat {
+	if c.Time.Layout == "" {
+		return utils.DefaultTimeFormat()
+	}
+	return c.Time
+}
+
+// TemplateConfig 按告警状态选择的模板名称，对应全局 templates 中的名称
+type TemplateConfig struct {
+	Firing   string `yaml:"firing"`
+	Resolved string `yaml:"resolved"`
+}
+
+// textFormatter 按批次格式化告警文本，记录第一个模板渲染错误
+// 拆分批次时需要多次格式化，调用方在生成全部消息后通过 Err 检查，渲染失败时不发送错误信息文本
+type textFormatter struct {
+	format func(template.Data) (string, error)
+	err    error
+}
+
+// messageFormatter 返回接收者使用的格式化器，配置了自定义模板时使用模板，否则按 tf 使用内置格式 builtin
+func messageFormatter(tmpl *utils.MessageTemplate, builtin func(template.Data, utils.TimeFormat) string, tf utils.TimeFormat) *textFormatter {
+	return &textFormatter{format: tmpl.Formatter(func(batch template.Data) string {
+		return builtin(batch, tf)
+	})}
+}
+
+// Text 格式化一批告警，渲染失败时返回空字符串并记录错误
+func (f *textFormatter) Text(batch template.Data) string {
+	text, err := f.format(batch)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return text
+}
+
+// Err 返回第一个模板渲染错误
+func (f *textFormatter) Err() error {
+	return f.err
+}
+
+// payloadFormatter 按批次生成 Slack、Teams 等卡片类渠道的消息，与 textFormatter 一样记录第一个模板渲染错误
+// build 生成一批告警的消息，size 返回用于分批的消息长度
+type payloadFormatter[T any] struct {
+	build func(template.Data) (T, error)
+	size  func(T) int
+	err   error
+}
+
+// newPayloadFormatter 返回卡片类渠道使用的格式化器
+func newPayloadFormatter[T any](build func(template.Data) (T, error), size func(T) int) *payloadFormatter[T] {
+	return &payloadFormatter[T]{build: build, size: size}
+}
+
+// Payload 生成一批告警的消息，渲染失败时记录错误
+func (f *payloadFormatter[T]) Payload(batch template.Data) T {
+	payload, err := f.build(batch)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return payload
+}
+
+// Size 返回一批告警的消息长度，用作分批的 measure 函数
+func (f *payloadFormatter[T]) Size(batch template.Data) int {
+	return f.size(f.Payload(batch))
+}
+
+// Messages 为每批告警生成一条消息，有模板渲染错误时返回错误
+func (f *payloadFormatter[T]) Messages(batches []template.Data) ([]Message, error) {
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: f.Payload(batch)})
+	}
+	if err := f.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Err 返回第一个模板渲染错误
+func (f *payloadFormatter[T]) Err() error {
+	return f.err
+}
+
+// jsonLength 返回消息 JSON 编码后的字节数，用于按请求体长度分批
+func jsonLength[T any](message T) int {
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return 0
+	}
+	return len(jsonData)
+}
+
+// alertSummary 消息顶部的摘要，用于 Slack、Teams 等按告警生成卡片的渠道
+func alertSummary(firing, resolved int) string {
+	switch {
+	case firing > 0 && resolved > 0:
+		return fmt.Sprintf("🔥 Prometheus告警通知：%d 个告警中，%d 个已恢复", firing, resolved)
+	case firing > 0:
+		return fmt.Sprintf("🔥 Prometheus告警通知：%d 个告警中", firing)
+	default:
+		return fmt.Sprintf("✅ Prometheus告警恢复：%d 个已恢复", resolved)
+	}
+}
+
+// Factory 根据配置创建通知器
+type Factory func(name string, cfg Config) (Notifier, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register 注册一种通知器类型，重复注册会直接 panic
+func Register(notifierType string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("notifier: Register factory is nil")
+	}
+	if _, dup := factories[notifierType]; dup {
+		panic("notifier: Register called twice for type " + notifierType)
+	}
+	factories[notifierType] = factory
+}
+
+// IsRegistered 判断通知器类型是否已注册
+func IsRegistered(notifierType string) bool {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	_, ok := factories[notifierType]
+	return ok
+}
+
+// Types 返回所有已注册的通知器类型
+func Types() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	types := make([]string, 0, len(factories))
+	for t := range factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New 根据配置中的类型创建名为 name 的通知器
+func New(name string, cfg Config) (Notifier, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[cfg.Type]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("未知客户端类型: %s", cfg.Type)
+	}
+	return factory(name, cfg)
+}
+
+// DispatchError 多条消息发送失败时的合并错误，任一失败可重试时整体可重试
+// Permanent 为所在消息永久失败的告警指纹，整体重试时这些告警可单独进入死信
+type DispatchError struct {
+	Errs      []error
+	Retryable bool
+	Permanent []string
+}
+
+func (e *DispatchError) Error() string {
+	return errors.Join(e.Errs...).Error()
+}
+
+func (e *DispatchError) Unwrap() []error {
+	return e.Errs
+}
+
+// PermanentErr 返回不可重试的发送错误，没有时返回 nil
+func (e *DispatchError) PermanentErr() error {
+	var errs []error
+	for _, err := range e.Errs {
+		if !IsRetryable(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dispatch 格式化尚未发送的告警并逐条发送，delivered 为已发送告警的指纹，重新投递时跳过这些告警
+// 返回全部已发送告警的指纹、本次发送成功的消息数、本次消息总数和发送错误，多条消息失败时返回 *DispatchError
+// 告警所在的全部消息都发送成功后才视为已发送，同一告警拆分到多条消息（如多个设备、多个群组）时可能重复发送
+// 多条消息之间添加小延迟，避免触发平台频率限制；ctx 取消时停止发送其余消息，未发送的告警保留待重新投递
+func Dispatch(ctx context.Context, n Notifier, data template.Data, delivered []string) ([]string, int, int, error) {
+	skip := make(map[string]bool, len(delivered))
+	for _, fingerprint := range delivered {
+		skip[fingerprint] = true
+	}
+
+	pending := data
+	pending.Alerts = nil
+	for _, alert := range data.Alerts {
+		if !skip[utils.AlertFingerprint(alert)] {
+			pending.Alerts = append(pending.Alerts, alert)
+		}
+	}
+	if len(pending.Alerts) == 0 {
+		return delivered, 0, 0, nil
+	}
+	if len(pending.Alerts) < len(data.Alerts) {
+		log.Printf("[%s] %d 个告警已发送，重新发送其余 %d 个告警", n.Name(), len(data.Alerts)-len(pending.Alerts), len(pending.Alerts))
+	}
+	pending.Status = utils.GroupStatus(pending.Alerts, data.Status)
+
+	messages, err := n.Format(pending)
+	if err != nil {
+		return delivered, 0, 0, fmt.Errorf("[%s] 格式化消息失败: %w", n.Name(), err)
+	}
+
+	if len(messages) > 1 {
+		log.Printf("[%s] 告警分为 %d 批发送", n.Name(), len(messages))
+	}
+
+	failed := make(map[string]bool)
+	permanent := make(map[string]bool)
+	sent := 0
+	var errs []error
+	for i, message := range messages {
+		if err := n.Send(ctx, message.Payload); err != nil {
+			log.Printf("[%s] 第 %d/%d 批消息发送失败: %v", n.Name(), i+1, len(messages), err)
+			errs = append(errs, err)
+			retryable := IsRetryable(err)
+			for _, alert := range message.Alerts {
+				fingerprint := utils.AlertFingerprint(alert)
+				failed[fingerprint] = true
+				if !retryable {
+					permanent[fingerprint] = true
+				}
+			}
+		} else {
+			log.Printf("[%s] 第 %d/%d 批消息发送成功", n.Name(), i+1, len(messages))
+			sent++
+		}
+
+		if i < len(messages)-1 {
+			select {
+			case <-time.After(200 * time.Millisecond):
+			case <-ctx.Done():
+				errs = append(errs, &SendError{Retryable: true, Err: fmt.Errorf("[%s] 发送被取消，剩余 %d 批消息未发送: %w", n.Name(), len(messages)-i-1, ctx.Err())})
+				for _, rest := range messages[i+1:] {
+					for _, alert := range rest.Alerts {
+						failed[utils.AlertFingerprint(alert)] = true
+					}
+				}
+				return dispatched(delivered, pending, failed), sent, len(messages), dispatchError(errs, permanent)
+			}
+		}
+	}
+
+	return dispatched(delivered, pending, failed), sent, len(messages), dispatchError(errs, permanent)
+}
+
+// dispatchError 合并各条消息的发送错误，只有一个错误时原样返回
+func dispatchError(errs []error, permanent map[string]bool) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+
+	dispatchErr := &DispatchError{Errs: errs}
+	for _, err := range errs {
+		if IsRetryable(err) {
+			dispatchErr.Retryable = true
+		}
+	}
+	for fingerprint := range permanent {
+		dispatchErr.Permanent = append(dispatchErr.Permanent, fingerprint)
+	}
+	sort.Strings(dispatchErr.Permanent)
+	return dispatchErr
+}
+
+// dispatched 返回已发送告警的指纹，failed 为所在消息未发送成功的告警
+// 不在任何消息中的告警（如短信不通知已恢复的告警）同样视为已发送
+func dispatched(delivered []string, pending template.Data, failed map[string]bool) []string {
+	result := append([]string{}, delivered...)
+	for _, alert := range pending.Alerts {
+		if fingerprint := utils.AlertFingerprint(alert); !failed[fingerprint] {
+			result = append(result, fingerprint)
+		}
+	}
+	return result
+}
+
+type attemptCounterKey struct{}
+
+// WithAttemptCounter 返回记录 HTTP 发送次数（含重试）的 context
+func WithAttemptCounter(ctx context.Context) (context.Context, *atomic.Int32) {
+	counter := &atomic.Int32{}
+	return context.WithValue(ctx, attemptCounterKey{}, counter), counter
+}
+
+// countAttempt 记录一次发送尝试
+func countAttempt(ctx context.Context) {
+	if counter, ok := ctx.Value(attemptCounterKey{}).(*atomic.Int32); ok {
+		counter.Add(1)
+	}
+}
@@ -0,0 +1,181 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// fakeNotifier 每批 batchSize 个告警，failOnce 中的告警名称所在的消息第一次发送失败（可重试），
+// failAlways 中的告警名称所在的消息总是永久失败
+type fakeNotifier struct {
+	batchSize  int
+	failOnce   map[string]bool
+	failAlways map[string]bool
+	sent       []string
+}
+
+func (f *fakeNotifier) Name() string        { return "fake" }
+func (f *fakeNotifier) MaxMessageSize() int { return 0 }
+func (f *fakeNotifier) TestConnection() error {
+	return nil
+}
+
+func (f *fakeNotifier) Format(data template.Data) ([]Message, error) {
+	var messages []Message
+	for _, batch := range utils.ChunkAlerts(data, f.batchSize) {
+		names := make([]string, 0, len(batch.Alerts))
+		for _, alert := range batch.Alerts {
+			names = append(names, alert.Labels["alertname"])
+		}
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: names})
+	}
+	return messages, nil
+}
+
+func (f *fakeNotifier) Send(_ context.Context, message interface{}) error {
+	names := message.([]string)
+	for _, name := range names {
+		if f.failAlways[name] {
+			return &SendError{Err: errors.New("permanent failure")}
+		}
+		if f.failOnce[name] {
+			delete(f.failOnce, name)
+			return &SendError{Retryable: true, Err: errors.New("temporary failure")}
+		}
+	}
+	f.sent = append(f.sent, names...)
+	return nil
+}
+
+func testAlerts(names ...string) template.Data {
+	data := template.Data{Status: "firing"}
+	for _, name := range names {
+		data.Alerts = append(data.Alerts, template.Alert{
+			Status: "firing",
+			Labels: template.KV{"alertname": name},
+		})
+	}
+	return data
+}
+
+// capturedRequest 模拟服务收到的一次请求
+type capturedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// captureServer 记录收到的全部请求并返回 response 作为响应体
+type captureServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []capturedRequest
+}
+
+func newCaptureServer(t *testing.T, response string) *captureServer {
+	t.Helper()
+	s := &captureServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, capturedRequest{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header, Body: body})
+		s.mu.Unlock()
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests 返回已收到的请求
+func (s *captureServer) Requests() []capturedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]capturedRequest(nil), s.requests...)
+}
+
+func TestDispatchRedeliversOnlyUndeliveredAlerts(t *testing.T) {
+	data := testAlerts("a", "b", "c", "d")
+	n := &fakeNotifier{batchSize: 2, failOnce: map[string]bool{"c": true}}
+
+	delivered, sent, total, err := Dispatch(context.Background(), n, data, nil)
+	if err == nil {
+		t.Fatal("expected first dispatch to fail")
+	}
+	if sent != 1 || total != 2 {
+		t.Fatalf("sent/total = %d/%d, want 1/2", sent, total)
+	}
+	if len(delivered) != 2 {
+		t.Fatalf("delivered = %v, want fingerprints of a and b", delivered)
+	}
+
+	// 重新投递时拆分方式改变，已发送的告警仍然按指纹跳过
+	n.batchSize = 3
+	delivered, sent, total, err = Dispatch(context.Background(), n, data, delivered)
+	if err != nil {
+		t.Fatalf("second dispatch: %v", err)
+	}
+	if sent != 1 || total != 1 || len(delivered) != 4 {
+		t.Fatalf("sent/total/delivered = %d/%d/%d, want 1/1/4", sent, total, len(delivered))
+	}
+
+	want := []string{"a", "b", "c", "d"}
+	if len(n.sent) != len(want) {
+		t.Fatalf("sent alerts = %v, want %v", n.sent, want)
+	}
+	for i := range want {
+		if n.sent[i] != want[i] {
+			t.Fatalf("sent alerts = %v, want %v", n.sent, want)
+		}
+	}
+}
+
+func TestDispatchAllDelivered(t *testing.T) {
+	data := testAlerts("a")
+	n := &fakeNotifier{batchSize: 1}
+	delivered := []string{utils.AlertFingerprint(data.Alerts[0])}
+
+	got, sent, total, err := Dispatch(context.Background(), n, data, delivered)
+	if err != nil || sent != 0 || total != 0 || len(got) != 1 || len(n.sent) != 0 {
+		t.Fatalf("Dispatch = %v, %d, %d, %v; sent %v", got, sent, total, err, n.sent)
+	}
+}
+
+func TestDispatchCombinesRetryableAndPermanentFailures(t *testing.T) {
+	for _, order := range [][]string{{"transient", "permanent", "ok"}, {"permanent", "transient", "ok"}} {
+		data := testAlerts(order...)
+		n := &fakeNotifier{batchSize: 1, failOnce: map[string]bool{"transient": true}, failAlways: map[string]bool{"permanent": true}}
+
+		delivered, _, _, err := Dispatch(context.Background(), n, data, nil)
+		var dispatchErr *DispatchError
+		if !errors.As(err, &dispatchErr) || !IsRetryable(err) {
+			t.Fatalf("order %v: err = %v, want retryable DispatchError", order, err)
+		}
+		permanent := utils.AlertFingerprint(testAlerts("permanent").Alerts[0])
+		if len(dispatchErr.Permanent) != 1 || dispatchErr.Permanent[0] != permanent {
+			t.Errorf("order %v: permanent = %v, want only the permanently failing alert", order, dispatchErr.Permanent)
+		}
+		if got := dispatchErr.PermanentErr(); got == nil || got.Error() != "permanent failure" {
+			t.Errorf("order %v: permanent error = %v", order, got)
+		}
+		if len(delivered) != 1 {
+			t.Errorf("order %v: delivered = %v, want only the ok alert", order, delivered)
+		}
+	}
+}
+
+func TestDispatchAllPermanentNotRetryable(t *testing.T) {
+	n := &fakeNotifier{batchSize: 1, failAlways: map[string]bool{"a": true, "b": true}}
+	_, _, _, err := Dispatch(context.Background(), n, testAlerts("a", "b"), nil)
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("err = %v, want non-retryable error", err)
+	}
+}
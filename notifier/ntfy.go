@@ -0,0 +1,150 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// ntfyAPIURL ntfy 公共服务地址
+const ntfyAPIURL = "https://ntfy.sh"
+
+// ntfy 消息优先级：1 min ~ 5 max（urgent），4、5 级会持续振动并弹出通知
+const (
+	ntfyPriorityMin     = 1
+	ntfyPriorityLow     = 2
+	ntfyPriorityDefault = 3
+	ntfyPriorityHigh    = 4
+	ntfyPriorityMax     = 5
+)
+
+// NtfyMessage ntfy JSON 发布请求体，发送到服务根地址
+type NtfyMessage struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title,omitempty"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags,omitempty"`
+	Click    string   `json:"click,omitempty"`
+}
+
+// NtfyNotifier ntfy 手机推送通知器，支持公共服务和自建服务
+type NtfyNotifier struct {
+	name       string
+	apiURL     string
+	topic      string
+	header     http.Header
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+func init() {
+	Register("ntfy", NewNtfyNotifier)
+}
+
+// NewNtfyNotifier 创建 ntfy 通知器，配置了 token 时以 Bearer 令牌鉴权
+func NewNtfyNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("接收者 %s 的 topic 未配置", name)
+	}
+
+	header := make(http.Header)
+	if cfg.Token != "" {
+		token, err := resolveSecret(cfg.Token)
+		if err != nil {
+			return nil, fmt.Errorf("接收者 %s 的 token 读取失败: %w", name, err)
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = ntfyAPIURL
+	}
+
+	n := &NtfyNotifier{
+		name:       name,
+		apiURL:     strings.TrimRight(apiURL, "/") + "/",
+		topic:      cfg.Topic,
+		header:     header,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	// ntfy 以 HTTP 状态码返回错误，限流时返回 429
+	n.sender = NewSender(name, cfg.Delivery, nil)
+	return n, nil
+}
+
+func (n *NtfyNotifier) Name() string {
+	return n.name
+}
+
+func (n *NtfyNotifier) MaxMessageSize() int {
+	return pushMaxLength
+}
+
+// Format 标题为告警分组和数量，正文超过长度限制时分批推送，优先级取每批中的最高告警级别
+func (n *NtfyNotifier) Format(data template.Data) ([]Message, error) {
+	format := messageFormatter(n.template, formatPushText, n.timeFormat)
+	batches := utils.SplitAlerts(data, n.MaxMessageSize(), format.Text)
+
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		severity := pushSeverity(batch)
+		tags := []string{"rotating_light"}
+		if severity == "" {
+			tags = []string{"white_check_mark"}
+		}
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: NtfyMessage{
+			Topic:    n.topic,
+			Title:    pushTitle(batch),
+			Message:  format.Text(batch),
+			Priority: ntfyPriority(severity),
+			Tags:     tags,
+			Click:    pushClickURL(batch),
+		}})
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (n *NtfyNotifier) Send(ctx context.Context, message interface{}) error {
+	return n.sender.Retry(ctx, func(ctx context.Context) error {
+		return n.sender.postHeader(ctx, n.apiURL, n.header, message)
+	})
+}
+
+// TestConnection 以最低优先级发送测试消息，不振动也不弹出通知
+func (n *NtfyNotifier) TestConnection() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return n.sender.postHeader(ctx, n.apiURL, n.header, NtfyMessage{
+		Topic:    n.topic,
+		Message:  "ntfy连通性测试",
+		Priority: ntfyPriorityMin,
+	})
+}
+
+// ntfyPriority 将 MapSeverity 的级别映射为 ntfy 优先级，P0 为最高优先级（urgent），已恢复为低优先级
+func ntfyPriority(severity string) int {
+	switch severity {
+	case "P0":
+		return ntfyPriorityMax
+	case "P1":
+		return ntfyPriorityHigh
+	case "P2":
+		return ntfyPriorityDefault
+	case "P3", "":
+		return ntfyPriorityLow
+	default:
+		return ntfyPriorityDefault
+	}
+}
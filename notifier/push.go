@@ -0,0 +1,100 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// pushMaxLength 手机推送正文长度限制：ntfy 超过4096字节的消息会转为附件，Bark 受 APNs 4KB 负载限制，统一留一些安全边界
+const pushMaxLength = 3500
+
+// PushConfig ntfy、Gotify、Bark 手机推送接收者配置，服务地址使用 api_url，ntfy 主题使用 topic，令牌使用 token
+type PushConfig struct {
+	// Bark 设备 key，可配置多个
+	DeviceKeys []string `yaml:"device_keys"`
+}
+
+// pushSeverity 返回一批告警中告警中告警的最高级别（P0~P3），全部已恢复时返回空字符串
+func pushSeverity(batch template.Data) string {
+	firing, _ := utils.SplitByStatus(batch)
+	if len(firing) == 0 {
+		return ""
+	}
+	return utils.MapSeverity(utils.HighestSeverity(firing))
+}
+
+// pushTitle 推送标题：最高级别、告警分组名称和告警数量
+func pushTitle(batch template.Data) string {
+	firing, resolved := utils.SplitByStatus(batch)
+	group := pushGroupName(batch)
+
+	switch {
+	case len(firing) > 0 && len(resolved) > 0:
+		return fmt.Sprintf("[%s] %s：%d 个告警中，%d 个已恢复", pushSeverity(batch), group, len(firing), len(resolved))
+	case len(firing) > 0:
+		return fmt.Sprintf("[%s] %s：%d 个告警中", pushSeverity(batch), group, len(firing))
+	default:
+		return fmt.Sprintf("[已恢复] %s：%d 个已恢复", group, len(resolved))
+	}
+}
+
+// pushGroupName 告警分组名称：优先使用分组标签中的 alertname，其次为全部分组标签的值，均为空时使用公共标签中的 alertname
+func pushGroupName(batch template.Data) string {
+	if name := batch.GroupLabels["alertname"]; name != "" {
+		return name
+	}
+	if len(batch.GroupLabels) > 0 {
+		return strings.Join(batch.GroupLabels.SortedPairs().Values(), ",")
+	}
+	if name := batch.CommonLabels["alertname"]; name != "" {
+		return name
+	}
+	return "Prometheus告警"
+}
+
+// pushClickURL 点击推送时打开的地址：第一个告警的图表地址，没有时使用 Alertmanager 地址
+func pushClickURL(batch template.Data) string {
+	for _, alert := range batch.Alerts {
+		if alert.GeneratorURL != "" {
+			return alert.GeneratorURL
+		}
+	}
+	return batch.ExternalURL
+}
+
+// formatPushText 手机推送内置正文格式，纯文本，每个告警只保留关键信息以适应锁屏通知的显示空间
+func formatPushText(data template.Data, tf utils.TimeFormat) string {
+	var builder strings.Builder
+	now := time.Now()
+	firing, resolved := utils.SplitByStatus(data)
+
+	for _, alert := range firing {
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fmt.Sprintf("🔥 [%s] %s\n", utils.MapSeverity(alert.Labels["severity"]), alert.Labels["alertname"]))
+		if instance := alert.Labels["instance"]; instance != "" {
+			builder.WriteString(fmt.Sprintf("实例: %s\n", instance))
+		}
+		if summary := alert.Annotations["summary"]; summary != "" {
+			builder.WriteString(fmt.Sprintf("摘要: %s\n", summary))
+		}
+		builder.WriteString(fmt.Sprintf("触发时间: %s（已持续 %s）\n", tf.Format(alert.StartsAt), utils.FormatDuration(utils.AlertDuration(alert, data.Status, now))))
+	}
+
+	for _, alert := range resolved {
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fmt.Sprintf("✅ [已恢复] %s\n", alert.Labels["alertname"]))
+		if instance := alert.Labels["instance"]; instance != "" {
+			builder.WriteString(fmt.Sprintf("实例: %s\n", instance))
+		}
+		builder.WriteString(fmt.Sprintf("恢复时间: %s（持续 %s）\n", tf.Format(alert.EndsAt), utils.FormatDuration(utils.AlertDuration(alert, data.Status, now))))
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
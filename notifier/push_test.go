@@ -0,0 +1,120 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func pushAlerts() template.Data {
+	return template.Data{
+		Status:      "firing",
+		GroupLabels: template.KV{"alertname": "NodeDown"},
+		Alerts: template.Alerts{
+			{Status: "firing", Labels: template.KV{"alertname": "NodeDown", "instance": "node-1", "severity": "emergency"},
+				Annotations: template.KV{"summary": "节点失联"}, GeneratorURL: "http://prometheus/graph"},
+			{Status: "resolved", Labels: template.KV{"alertname": "NodeDown", "instance": "node-2", "severity": "emergency"}},
+		},
+	}
+}
+
+func TestNtfyPayload(t *testing.T) {
+	server := newCaptureServer(t, `{"id":"x"}`)
+	t.Setenv("NTFY_TOKEN", "tk_test")
+	n, err := NewNtfyNotifier("ntfy", Config{APIURL: server.URL + "/", Topic: "oncall", Token: "env:NTFY_TOKEN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := Dispatch(context.Background(), n, pushAlerts(), nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Path != "/" || requests[0].Header.Get("Authorization") != "Bearer tk_test" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	var msg NtfyMessage
+	if err := json.Unmarshal(requests[0].Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != "oncall" || msg.Priority != ntfyPriorityMax || msg.Click != "http://prometheus/graph" ||
+		msg.Title != "[P0] NodeDown：1 个告警中，1 个已恢复" || len(msg.Tags) != 1 || msg.Tags[0] != "rotating_light" {
+		t.Errorf("message = %+v", msg)
+	}
+	if !strings.Contains(msg.Message, "🔥 [P0] NodeDown") || !strings.Contains(msg.Message, "✅ [已恢复] NodeDown") {
+		t.Errorf("text = %s", msg.Message)
+	}
+}
+
+func TestGotifyPayload(t *testing.T) {
+	server := newCaptureServer(t, `{"id":1}`)
+	n, err := NewGotifyNotifier("gotify", Config{APIURL: server.URL, Token: "app-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := pushAlerts()
+	data.Alerts = data.Alerts[1:]
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	requests := server.Requests()
+	// 令牌只通过请求头传递
+	if len(requests) != 1 || requests[0].Path != "/message" || requests[0].Header.Get("X-Gotify-Key") != "app-token" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	var msg GotifyMessage
+	if err := json.Unmarshal(requests[0].Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Priority != gotifyPriorityLow || msg.Title != "[已恢复] NodeDown：1 个已恢复" || msg.Extras != nil {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestBarkPayloadPerDevice(t *testing.T) {
+	server := newCaptureServer(t, `{"code":200,"message":"success"}`)
+	n, err := NewBarkNotifier("bark", Config{APIURL: server.URL, PushConfig: PushConfig{DeviceKeys: []string{"key-a", "key-b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := Dispatch(context.Background(), n, pushAlerts(), nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want one per device", len(requests))
+	}
+	for i, key := range []string{"key-a", "key-b"} {
+		if requests[i].Path != "/push" {
+			t.Errorf("path = %s", requests[i].Path)
+		}
+		var msg BarkMessage
+		if err := json.Unmarshal(requests[i].Body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		// P0 告警以 critical 级别最大音量重复响铃
+		if msg.DeviceKey != key || msg.Level != barkLevelCritical || msg.Volume != 10 || msg.Call != "1" || msg.Group != "Prometheus" {
+			t.Errorf("message = %+v", msg)
+		}
+	}
+}
+
+func TestBarkVendorError(t *testing.T) {
+	server := newCaptureServer(t, `{"code":400,"message":"failed to get device token"}`)
+	n, err := NewBarkNotifier("bark", Config{APIURL: server.URL, PushConfig: PushConfig{DeviceKeys: []string{"key-a"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), BarkMessage{DeviceKey: "key-a", Body: "test"})
+	var vendorErr *VendorError
+	if !errors.As(err, &vendorErr) || vendorErr.Code != 400 || IsRetryable(err) {
+		t.Fatalf("err = %v, want non-retryable vendor error 400", err)
+	}
+}
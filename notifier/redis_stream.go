@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisRetryablePrefixes 可重试的 Redis 服务端错误：加载数据、集群迁移或故障切换中
+var redisRetryablePrefixes = []string{"LOADING", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN", "READONLY"}
+
+// redisStreamPublisher 使用 XADD 将告警追加到 Redis Stream，每条记录包含 fingerprint、id 和 alert 三个字段，
+// 消费端可使用 XREAD 或消费者组读取
+type redisStreamPublisher struct {
+	name   string
+	stream string
+	maxLen int64
+	client redis.UniversalClient
+}
+
+func init() {
+	RegisterBus("redis_stream", newRedisStreamPublisher)
+}
+
+// newRedisStreamPublisher 创建 Redis 客户端，brokers 未配置时连接 127.0.0.1:6379，连接在首次发布时建立
+func newRedisStreamPublisher(name string, cfg Config) (Publisher, error) {
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("接收者 %s 的 topic 未配置", name)
+	}
+	if cfg.StreamMaxLen < 0 {
+		return nil, fmt.Errorf("接收者 %s 的 stream_max_len 不能为负数", name)
+	}
+
+	password, err := busPassword(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	addrs := cfg.Brokers
+	if len(addrs) == 0 {
+		addrs = []string{"127.0.0.1:6379"}
+	}
+
+	return &redisStreamPublisher{
+		name:   name,
+		stream: cfg.Topic,
+		maxLen: cfg.StreamMaxLen,
+		client: redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:      addrs,
+			Username:   cfg.Username,
+			Password:   password,
+			TLSConfig:  cfg.tlsConfig(),
+			ClientName: "alert-webhook",
+			// 重试由 Sender 按 delivery 配置处理
+			MaxRetries: -1,
+		}),
+	}, nil
+}
+
+// Publish 以 pipeline 批量执行 XADD，配置了 stream_max_len 时按近似长度裁剪
+func (p *redisStreamPublisher) Publish(ctx context.Context, messages []BusMessage) error {
+	pipe := p.client.Pipeline()
+	for _, message := range messages {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: p.stream,
+			MaxLen: p.maxLen,
+			Approx: p.maxLen > 0,
+			Values: []interface{}{"fingerprint", message.Key, "id", message.ID, "alert", message.Value},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return busError(p.name, redisRetryable(err), err)
+	}
+	return nil
+}
+
+func (p *redisStreamPublisher) Ping(ctx context.Context) error {
+	return p.client.Ping(ctx).Err()
+}
+
+func (p *redisStreamPublisher) Close() error {
+	return p.client.Close()
+}
+
+// redisRetryable 服务端返回的错误（如 key 类型错误、无权限）不重试，网络错误和超时重试
+func redisRetryable(err error) bool {
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) {
+		return true
+	}
+	for _, prefix := range redisRetryablePrefixes {
+		if strings.HasPrefix(redisErr.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
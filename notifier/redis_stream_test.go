@@ -0,0 +1,101 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/alertmanager/template"
+)
+
+func newTestRedisStream(t *testing.T, server *miniredis.Miniredis) Notifier {
+	t.Helper()
+	maxRetries := 0
+	n, err := New("alert-stream", Config{
+		Type:      "redis_stream",
+		Topic:     "alerts",
+		Delivery:  DeliveryConfig{MaxRetries: &maxRetries},
+		BusConfig: BusConfig{Brokers: []string{server.Addr()}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRedisStreamPublish(t *testing.T) {
+	server := miniredis.RunT(t)
+	n := newTestRedisStream(t, server)
+
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	data := template.Data{
+		Status:      "firing",
+		GroupLabels: template.KV{"alertname": "HighCPU"},
+		Alerts: template.Alerts{
+			{Status: "firing", Labels: template.KV{"alertname": "HighCPU", "instance": "node-1"}, StartsAt: start},
+			{Status: "resolved", Labels: template.KV{"alertname": "HighCPU", "instance": "node-2"}, StartsAt: start, EndsAt: start.Add(time.Minute)},
+		},
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	entries, err := server.Stream("alerts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("stream entries = %d, want one per alert", len(entries))
+	}
+	for i, entry := range entries {
+		fields := map[string]string{}
+		for j := 0; j+1 < len(entry.Values); j += 2 {
+			fields[entry.Values[j]] = entry.Values[j+1]
+		}
+
+		alert := data.Alerts[i]
+		fingerprint := utils.AlertFingerprint(alert)
+		if fields["fingerprint"] != fingerprint {
+			t.Errorf("fingerprint = %q, want %q", fields["fingerprint"], fingerprint)
+		}
+		if want := fingerprint + "-" + alert.Status + "-" + strconv.FormatInt(start.Unix(), 10); fields["id"] != want {
+			t.Errorf("id = %q, want %q", fields["id"], want)
+		}
+
+		var busAlert BusAlert
+		if err := json.Unmarshal([]byte(fields["alert"]), &busAlert); err != nil {
+			t.Fatalf("alert field is not JSON: %v", err)
+		}
+		if busAlert.Receiver != "alert-stream" || busAlert.Status != alert.Status || busAlert.Labels["instance"] != alert.Labels["instance"] ||
+			busAlert.GroupLabels["alertname"] != "HighCPU" || !busAlert.StartsAt.Equal(start) {
+			t.Errorf("alert = %+v", busAlert)
+		}
+	}
+}
+
+func TestRedisStreamServerErrorNotRetryable(t *testing.T) {
+	server := miniredis.RunT(t)
+	n := newTestRedisStream(t, server)
+	// stream 名称已被其他类型的 key 占用，XADD 返回 WRONGTYPE
+	server.Set("alerts", "occupied")
+
+	_, _, _, err := Dispatch(context.Background(), n, testAlerts("a"), nil)
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("err = %v, want non-retryable WRONGTYPE error", err)
+	}
+}
+
+func TestRedisStreamUnavailableRetryable(t *testing.T) {
+	server := miniredis.RunT(t)
+	n := newTestRedisStream(t, server)
+	server.Close()
+
+	_, _, _, err := Dispatch(context.Background(), n, testAlerts("a"), nil)
+	if err == nil || !IsRetryable(err) {
+		t.Fatalf("err = %v, want retryable connection error", err)
+	}
+}
@@ -0,0 +1,304 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DeliveryConfig 发送超时与重试配置，接收者未配置的字段使用全局默认值
+type DeliveryConfig struct {
+	// 单次请求超时（秒）
+	Timeout int `yaml:"timeout"`
+	// 最大重试次数，不含首次发送，配置为0表示不重试
+	MaxRetries *int `yaml:"max_retries"`
+	// 首次重试前的等待时间（秒），之后按指数增长
+	InitialInterval int `yaml:"initial_interval"`
+	// 重试等待时间上限（秒）
+	MaxInterval int `yaml:"max_interval"`
+}
+
+// DefaultDeliveryConfig 未配置 delivery 时使用的默认值
+func DefaultDeliveryConfig() DeliveryConfig {
+	maxRetries := 3
+	return DeliveryConfig{
+		Timeout:         10,
+		MaxRetries:      &maxRetries,
+		InitialInterval: 1,
+		MaxInterval:     30,
+	}
+}
+
+// Merge 用 defaults 补全未配置的字段
+func (d DeliveryConfig) Merge(defaults DeliveryConfig) DeliveryConfig {
+	if d.Timeout <= 0 {
+		d.Timeout = defaults.Timeout
+	}
+	if d.MaxRetries == nil {
+		d.MaxRetries = defaults.MaxRetries
+	}
+	if d.InitialInterval <= 0 {
+		d.InitialInterval = defaults.InitialInterval
+	}
+	if d.MaxInterval <= 0 {
+		d.MaxInterval = defaults.MaxInterval
+	}
+	return d
+}
+
+// SendError 发送失败的错误，携带是否可重试的分类信息
+type SendError struct {
+	// HTTP 状态码，网络错误时为0
+	StatusCode int
+	// 是否可以重试
+	Retryable bool
+	// 平台要求的重试等待时间，为0时使用指数退避
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *SendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// VendorError 平台以 HTTP 200 返回、在响应体中通过错误码表示的业务错误
+type VendorError struct {
+	Code    int
+	Message string
+}
+
+func (e *VendorError) Error() string {
+	return fmt.Sprintf("平台返回错误码: %d, 错误信息: %s", e.Code, e.Message)
+}
+
+// rateLimitRetryAfter 平台返回限流错误码时的重试等待时间，实际等待不超过 max_interval
+const rateLimitRetryAfter = 60 * time.Second
+
+// ResponseChecker 解析平台响应体，返回平台业务错误
+type ResponseChecker func(body []byte) error
+
+// newVendorError 将平台错误码包装为发送错误，限流错误码在等待后重试
+func newVendorError(client string, code int, message string, rateLimited bool) error {
+	sendErr := &SendError{
+		StatusCode: http.StatusOK,
+		Retryable:  rateLimited,
+		Err:        fmt.Errorf("[%s] %w", client, &VendorError{Code: code, Message: message}),
+	}
+	if rateLimited {
+		sendErr.RetryAfter = rateLimitRetryAfter
+	}
+	return sendErr
+}
+
+// IsRetryable 判断错误是否可以重试，未分类的错误视为不可重试；多条消息的合并错误中任一可重试即可重试
+func IsRetryable(err error) bool {
+	var dispatchErr *DispatchError
+	if errors.As(err, &dispatchErr) {
+		return dispatchErr.Retryable
+	}
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.Retryable
+	}
+	return false
+}
+
+// Sender 带超时、重试和错误分类的 HTTP JSON 发送器，每个接收者持有一个
+type Sender struct {
+	name       string
+	delivery   DeliveryConfig
+	httpClient *http.Client
+	check      ResponseChecker
+}
+
+// NewSender 创建发送器，delivery 中未配置的字段使用默认值
+// check 用于解析平台响应体中的错误码，为 nil 时只检查 HTTP 状态码
+func NewSender(name string, delivery DeliveryConfig, check ResponseChecker) *Sender {
+	delivery = delivery.Merge(DefaultDeliveryConfig())
+	return &Sender{
+		name:       name,
+		delivery:   delivery,
+		httpClient: &http.Client{Timeout: time.Duration(delivery.Timeout) * time.Second},
+		check:      check,
+	}
+}
+
+// SendAlert 发送告警到指定 webhook，可重试的错误按指数退避加随机抖动重试
+func (s *Sender) SendAlert(ctx context.Context, webhookURL string, message interface{}) error {
+	return s.Retry(ctx, func(ctx context.Context) error {
+		return s.post(ctx, webhookURL, message)
+	})
+}
+
+// Retry 执行发送操作，返回可重试错误（SendError.Retryable）时按指数退避加随机抖动重试
+// 用于 SMTP 等不通过 HTTP 发送的渠道复用重试配置
+func (s *Sender) Retry(ctx context.Context, send func(ctx context.Context) error) error {
+	maxRetries := *s.delivery.MaxRetries
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		countAttempt(ctx)
+		err = send(ctx)
+		if err == nil {
+			if attempt > 0 {
+				log.Printf("[%s] 第 %d 次重试发送成功", s.name, attempt)
+			}
+			return nil
+		}
+
+		if !IsRetryable(err) {
+			return err
+		}
+		if maxRetries == 0 {
+			return err
+		}
+		if attempt >= maxRetries {
+			return fmt.Errorf("[%s] 重试 %d 次后仍发送失败: %w", s.name, maxRetries, err)
+		}
+
+		wait := s.backoff(attempt, err)
+		log.Printf("[%s] 发送失败，%v 后进行第 %d/%d 次重试: %v", s.name, wait, attempt+1, maxRetries, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("[%s] 重试被取消: %w", s.name, err)
+		}
+	}
+}
+
+// Timeout 返回单次发送的超时时间
+func (s *Sender) Timeout() time.Duration {
+	return time.Duration(s.delivery.Timeout) * time.Second
+}
+
+// SendTestMessage 发送测试连接消息，只发送一次且不重试
+func (s *Sender) SendTestMessage(webhookURL string, message interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.post(ctx, webhookURL, message)
+}
+
+// backoff 计算第 attempt 次重试前的等待时间
+// 平台给出 RetryAfter 时优先使用（不超过上限），否则为 initial*2^attempt（不超过上限），并在 [d/2, d) 范围内随机抖动
+func (s *Sender) backoff(attempt int, err error) time.Duration {
+	initial := time.Duration(s.delivery.InitialInterval) * time.Second
+	maxInterval := time.Duration(s.delivery.MaxInterval) * time.Second
+
+	var sendErr *SendError
+	if errors.As(err, &sendErr) && sendErr.RetryAfter > 0 {
+		return min(sendErr.RetryAfter, maxInterval)
+	}
+
+	d := initial << uint(attempt)
+	if d <= 0 || d > maxInterval {
+		d = maxInterval
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// SendRequest 以指定方法、请求头和请求体发送请求，重试和错误分类与 SendAlert 相同
+// 用于请求体不是固定 JSON 结构的渠道，如通用 webhook
+func (s *Sender) SendRequest(ctx context.Context, method, webhookURL string, header http.Header, body []byte) error {
+	return s.Retry(ctx, func(ctx context.Context) error {
+		return s.do(ctx, method, webhookURL, header, body)
+	})
+}
+
+// post 将消息编码为 JSON 后发送一次请求
+func (s *Sender) post(ctx context.Context, webhookURL string, message interface{}) error {
+	return s.postHeader(ctx, webhookURL, nil, message)
+}
+
+// postHeader 与 post 相同，并附带额外的请求头，如鉴权令牌
+func (s *Sender) postHeader(ctx context.Context, webhookURL string, header http.Header, message interface{}) error {
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return &SendError{Err: fmt.Errorf("[%s] JSON编码失败: %w", s.name, err)}
+	}
+
+	header = header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Type", "application/json")
+	return s.do(ctx, http.MethodPost, webhookURL, header, jsonData)
+}
+
+// do 发送一次请求并对失败进行分类
+func (s *Sender) do(ctx context.Context, method, webhookURL string, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return &SendError{Err: fmt.Errorf("[%s] 创建请求失败: %w", s.name, stripURL(err))}
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return &SendError{Retryable: true, Err: fmt.Errorf("[%s] HTTP请求失败: %w", s.name, stripURL(err))}
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("[%s] 关闭响应体失败: %v", s.name, err)
+		}
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &SendError{Retryable: true, Err: fmt.Errorf("[%s] 读取响应失败: %w", s.name, err)}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &SendError{
+			StatusCode: resp.StatusCode,
+			Retryable:  resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Err: fmt.Errorf("[%s] 返回错误状态码: %d, 响应: %s",
+				s.name, resp.StatusCode, string(respBody)),
+		}
+	}
+
+	// 部分平台以 HTTP 200 返回业务错误，需要解析响应体中的错误码
+	if s.check != nil {
+		return s.check(respBody)
+	}
+
+	return nil
+}
+
+// stripURL 去掉错误信息中的请求地址，地址中可能包含 key、token 等密钥
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// parseRetryAfter 解析以秒为单位的 Retry-After 响应头
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
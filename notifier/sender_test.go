@@ -0,0 +1,165 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestSender 创建重试 maxRetries 次的发送器，重试等待使用最短的1秒间隔
+func newTestSender(maxRetries int, check ResponseChecker) *Sender {
+	return NewSender("test", DeliveryConfig{
+		Timeout:         5,
+		MaxRetries:      &maxRetries,
+		InitialInterval: 1,
+		MaxInterval:     1,
+	}, check)
+}
+
+// statusServer 依次返回 statuses 中的状态码，之后一直返回最后一个，retryAfter 不为空时附带 Retry-After 响应头
+func statusServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(requests.Add(1)) - 1
+		status := statuses[min(i, len(statuses)-1)]
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestSenderClassifiesStatusCodes(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		server, requests := statusServer(t, "", tt.status)
+		err := newTestSender(0, nil).SendAlert(context.Background(), server.URL, map[string]string{"text": "x"})
+
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("status %d: err = %v, want *SendError", tt.status, err)
+		}
+		if sendErr.StatusCode != tt.status || sendErr.Retryable != tt.retryable {
+			t.Errorf("status %d: StatusCode/Retryable = %d/%v, want %d/%v",
+				tt.status, sendErr.StatusCode, sendErr.Retryable, tt.status, tt.retryable)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("status %d: %d requests with max_retries 0, want 1", tt.status, requests.Load())
+		}
+	}
+}
+
+func TestSenderRetriesRetryableErrors(t *testing.T) {
+	server, requests := statusServer(t, "", http.StatusServiceUnavailable, http.StatusOK)
+	ctx, attempts := WithAttemptCounter(context.Background())
+
+	if err := newTestSender(2, nil).SendAlert(ctx, server.URL, map[string]string{"text": "x"}); err != nil {
+		t.Fatalf("SendAlert: %v", err)
+	}
+	if requests.Load() != 2 || attempts.Load() != 2 {
+		t.Errorf("requests/attempts = %d/%d, want 2/2", requests.Load(), attempts.Load())
+	}
+}
+
+func TestSenderDoesNotRetryClientErrors(t *testing.T) {
+	server, requests := statusServer(t, "", http.StatusBadRequest)
+
+	if err := newTestSender(3, nil).SendAlert(context.Background(), server.URL, map[string]string{"text": "x"}); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if requests.Load() != 1 {
+		t.Errorf("requests = %d, want 1", requests.Load())
+	}
+}
+
+func TestSenderVendorErrorWithHTTP200(t *testing.T) {
+	server, _ := statusServer(t, "", http.StatusOK)
+	check := func(body []byte) error {
+		return newVendorError("test", 45009, "rate limited", true)
+	}
+
+	err := newTestSender(0, check).SendAlert(context.Background(), server.URL, map[string]string{"text": "x"})
+	var vendorErr *VendorError
+	if !errors.As(err, &vendorErr) || vendorErr.Code != 45009 {
+		t.Fatalf("err = %v, want vendor error 45009", err)
+	}
+	if !IsRetryable(err) {
+		t.Error("rate-limit vendor error should be retryable")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	server, _ := statusServer(t, "120", http.StatusTooManyRequests)
+
+	err := newTestSender(0, nil).SendAlert(context.Background(), server.URL, map[string]string{"text": "x"})
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) || sendErr.RetryAfter != 120*time.Second {
+		t.Fatalf("err = %v, want RetryAfter 120s", err)
+	}
+
+	for _, value := range []string{"", "0", "-1", "Wed, 21 Oct 2015 07:28:00 GMT"} {
+		if got := parseRetryAfter(value); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %v, want 0", value, got)
+		}
+	}
+}
+
+func TestSenderBackoffCappedByMaxInterval(t *testing.T) {
+	maxRetries := 5
+	s := NewSender("test", DeliveryConfig{MaxRetries: &maxRetries, InitialInterval: 1, MaxInterval: 4}, nil)
+	maxInterval := 4 * time.Second
+
+	// Retry-After 超过上限时使用上限
+	retryAfter := &SendError{Retryable: true, RetryAfter: time.Minute, Err: errors.New("429")}
+	if got := s.backoff(0, retryAfter); got != maxInterval {
+		t.Errorf("backoff with Retry-After 1m = %v, want %v", got, maxInterval)
+	}
+	retryAfter.RetryAfter = 2 * time.Second
+	if got := s.backoff(0, retryAfter); got != 2*time.Second {
+		t.Errorf("backoff with Retry-After 2s = %v, want 2s", got)
+	}
+
+	// 指数退避在 [d/2, d] 范围内抖动，d 不超过上限
+	plain := &SendError{Retryable: true, Err: errors.New("503")}
+	for attempt := 0; attempt < 70; attempt++ {
+		want := maxInterval
+		if attempt < 2 {
+			want = time.Second << uint(attempt)
+		}
+		got := s.backoff(attempt, plain)
+		if got < want/2 || got > want {
+			t.Errorf("backoff(%d) = %v, want in [%v, %v]", attempt, got, want/2, want)
+		}
+	}
+}
+
+func TestSenderRetryStopsWhenContextCancelled(t *testing.T) {
+	server, requests := statusServer(t, "", http.StatusServiceUnavailable)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := newTestSender(3, nil).SendAlert(ctx, server.URL, map[string]string{"text": "x"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if requests.Load() > 1 {
+		t.Errorf("requests = %d after cancel, want at most 1", requests.Load())
+	}
+}
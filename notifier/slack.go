@@ -0,0 +1,188 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// slackMaxLength Slack 单条消息文本上限40000字符，按 JSON 长度留一些安全边界
+const slackMaxLength = 30000
+
+// slackMaxAttachments 单条消息的附件数量上限，Slack 建议不超过20个
+const slackMaxAttachments = 20
+
+// slackMaxSectionText Slack section 区块文本上限3000字符
+const slackMaxSectionText = 3000
+
+// slackMaxButtonURL Slack 按钮链接上限3000字符，超长的链接不生成按钮
+const slackMaxButtonURL = 3000
+
+// SlackMessage Slack incoming webhook 消息结构，每个告警一个按级别着色的附件
+type SlackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+}
+
+type SlackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+// SlackBlock Block Kit 区块，section 使用 Text，actions 使用 Elements
+type SlackBlock struct {
+	Type     string         `json:"type"`
+	Text     *SlackText     `json:"text,omitempty"`
+	Elements []SlackElement `json:"elements,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackElement actions 区块中的按钮
+type SlackElement struct {
+	Type  string    `json:"type"`
+	Text  SlackText `json:"text"`
+	URL   string    `json:"url"`
+	Style string    `json:"style,omitempty"`
+}
+
+// SlackNotifier Slack incoming webhook 通知器
+type SlackNotifier struct {
+	name       string
+	webhookURL string
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+func init() {
+	Register("slack", NewSlackNotifier)
+}
+
+// NewSlackNotifier 创建 Slack 通知器
+func NewSlackNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+	n := &SlackNotifier{
+		name:       name,
+		webhookURL: cfg.WebhookURL,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	// Slack 以 HTTP 状态码返回错误，响应体为纯文本，无需解析
+	n.sender = NewSender(name, cfg.Delivery, nil)
+	return n, nil
+}
+
+func (s *SlackNotifier) Name() string {
+	return s.name
+}
+
+func (s *SlackNotifier) MaxMessageSize() int {
+	return slackMaxLength
+}
+
+// Format 先按附件数量分组，再按消息长度分批
+func (s *SlackNotifier) Format(data template.Data) ([]Message, error) {
+	format := newPayloadFormatter(s.buildMessage, jsonLength[SlackMessage])
+	return format.Messages(utils.SplitAlertsLimited(data, slackMaxAttachments, s.MaxMessageSize(), format.Size))
+}
+
+func (s *SlackNotifier) Send(ctx context.Context, message interface{}) error {
+	return s.sender.SendAlert(ctx, s.webhookURL, message)
+}
+
+func (s *SlackNotifier) TestConnection() error {
+	return s.sender.SendTestMessage(s.webhookURL, SlackMessage{
+		Text: "Slack连通性测试",
+	})
+}
+
+// buildMessage 配置了自定义模板时以模板渲染结果作为消息文本，否则每个告警生成一个附件
+func (s *SlackNotifier) buildMessage(batch template.Data) (SlackMessage, error) {
+	text, ok, err := s.template.Render(batch)
+	if err != nil {
+		return SlackMessage{}, err
+	}
+	if ok {
+		return SlackMessage{Text: text}, nil
+	}
+
+	firing, resolved := utils.SplitByStatus(batch)
+	now := time.Now()
+
+	message := SlackMessage{Text: alertSummary(len(firing), len(resolved))}
+	for _, alert := range firing {
+		message.Attachments = append(message.Attachments, s.attachment(batch, alert, "firing", now))
+	}
+	for _, alert := range resolved {
+		message.Attachments = append(message.Attachments, s.attachment(batch, alert, "resolved", now))
+	}
+	return message, nil
+}
+
+// attachment 生成单个告警的附件：告警详情和查看图表、静默按钮
+func (s *SlackNotifier) attachment(batch template.Data, alert template.Alert, status string, now time.Time) SlackAttachment {
+	severity := alert.Labels["severity"]
+
+	var builder strings.Builder
+	if status == "firing" {
+		builder.WriteString(fmt.Sprintf("*[%s] %s*\n", slackEscape(utils.MapSeverity(severity)), slackEscape(alert.Labels["alertname"])))
+		builder.WriteString(fmt.Sprintf("*实例:* %s\n", slackEscape(alert.Labels["instance"])))
+		builder.WriteString(fmt.Sprintf("*摘要:* %s\n", slackEscape(alert.Annotations["summary"])))
+		if desc := alert.Annotations["description"]; desc != "" {
+			builder.WriteString(fmt.Sprintf("*描述:* %s\n", slackEscape(desc)))
+		}
+		builder.WriteString(fmt.Sprintf("*触发时间:* %s\n", s.timeFormat.Format(alert.StartsAt)))
+		builder.WriteString(fmt.Sprintf("*已持续:* %s", utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now))))
+	} else {
+		builder.WriteString(fmt.Sprintf("*[已恢复] %s*\n", slackEscape(alert.Labels["alertname"])))
+		builder.WriteString(fmt.Sprintf("*实例:* %s\n", slackEscape(alert.Labels["instance"])))
+		builder.WriteString(fmt.Sprintf("*恢复时间:* %s\n", s.timeFormat.Format(alert.EndsAt)))
+		builder.WriteString(fmt.Sprintf("*持续时间:* %s", utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now))))
+	}
+
+	blocks := []SlackBlock{{
+		Type: "section",
+		Text: &SlackText{Type: "mrkdwn", Text: utils.Truncate(slackMaxSectionText, builder.String())},
+	}}
+
+	var buttons []SlackElement
+	if alert.GeneratorURL != "" && len(alert.GeneratorURL) <= slackMaxButtonURL {
+		buttons = append(buttons, slackButton("查看图表", alert.GeneratorURL, "primary"))
+	}
+	// 已恢复的告警无需静默
+	if silence := utils.SilenceURL(batch.ExternalURL, alert.Labels); status == "firing" && silence != "" && len(silence) <= slackMaxButtonURL {
+		buttons = append(buttons, slackButton("静默", silence, "danger"))
+	}
+	if len(buttons) > 0 {
+		blocks = append(blocks, SlackBlock{Type: "actions", Elements: buttons})
+	}
+
+	return SlackAttachment{
+		Color:  utils.SeverityHexColor(severity, status),
+		Blocks: blocks,
+	}
+}
+
+func slackButton(text, url, style string) SlackElement {
+	return SlackElement{
+		Type:  "button",
+		Text:  SlackText{Type: "plain_text", Text: text},
+		URL:   url,
+		Style: style,
+	}
+}
+
+// slackEscape 转义 Slack mrkdwn 中的控制字符
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,204 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// mattermostMaxLength Mattermost 单条消息上限16383字符，按 JSON 字符数留一些安全边界
+const mattermostMaxLength = 15000
+
+// rocketChatMaxLength Rocket.Chat 单条消息默认上限5000字符，按 JSON 字符数留一些安全边界
+const rocketChatMaxLength = 4800
+
+// AttachmentMessage Slack 兼容的 incoming webhook 消息结构（旧版 attachments），用于 Mattermost 和 Rocket.Chat
+type AttachmentMessage struct {
+	Text        string       `json:"text"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment 每个告警一个按级别着色的附件
+type Attachment struct {
+	Fallback  string            `json:"fallback"`
+	Color     string            `json:"color"`
+	Title     string            `json:"title"`
+	TitleLink string            `json:"title_link,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Fields    []AttachmentField `json:"fields,omitempty"`
+}
+
+type AttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// AttachmentNotifier Slack 兼容 attachments 格式的 webhook 通知器，不同平台只有长度限制和响应格式不同
+type AttachmentNotifier struct {
+	name       string
+	platform   string
+	webhookURL string
+	maxLength  int
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+// rocketChatResponse Rocket.Chat webhook 响应体
+type rocketChatResponse struct {
+	Success *bool  `json:"success"`
+	Error   string `json:"error"`
+}
+
+func init() {
+	Register("mattermost", NewMattermostNotifier)
+	Register("rocketchat", NewRocketChatNotifier)
+}
+
+// NewMattermostNotifier 创建 Mattermost 通知器，Mattermost 以 HTTP 状态码返回错误
+func NewMattermostNotifier(name string, cfg Config) (Notifier, error) {
+	n, err := newAttachmentNotifier(name, "Mattermost", mattermostMaxLength, cfg)
+	if err != nil {
+		return nil, err
+	}
+	n.sender = NewSender(name, cfg.Delivery, nil)
+	return n, nil
+}
+
+// NewRocketChatNotifier 创建 Rocket.Chat 通知器
+func NewRocketChatNotifier(name string, cfg Config) (Notifier, error) {
+	n, err := newAttachmentNotifier(name, "Rocket.Chat", rocketChatMaxLength, cfg)
+	if err != nil {
+		return nil, err
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkRocketChatResponse)
+	return n, nil
+}
+
+func newAttachmentNotifier(name, platform string, maxLength int, cfg Config) (*AttachmentNotifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+	return &AttachmentNotifier{
+		name:       name,
+		platform:   platform,
+		webhookURL: cfg.WebhookURL,
+		maxLength:  maxLength,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}, nil
+}
+
+func (a *AttachmentNotifier) Name() string {
+	return a.name
+}
+
+func (a *AttachmentNotifier) MaxMessageSize() int {
+	return a.maxLength
+}
+
+// Format 按消息 JSON 的字符数分批
+func (a *AttachmentNotifier) Format(data template.Data) ([]Message, error) {
+	format := newPayloadFormatter(a.buildMessage, func(message AttachmentMessage) int {
+		jsonData, err := json.Marshal(message)
+		if err != nil {
+			return 0
+		}
+		return utf8.RuneCount(jsonData)
+	})
+	return format.Messages(utils.SplitAlertsFunc(data, a.MaxMessageSize(), format.Size))
+}
+
+func (a *AttachmentNotifier) Send(ctx context.Context, message interface{}) error {
+	return a.sender.SendAlert(ctx, a.webhookURL, message)
+}
+
+func (a *AttachmentNotifier) TestConnection() error {
+	return a.sender.SendTestMessage(a.webhookURL, AttachmentMessage{
+		Text: a.platform + "连通性测试",
+	})
+}
+
+// buildMessage 配置了自定义模板时以模板渲染结果作为消息文本，否则每个告警生成一个附件
+func (a *AttachmentNotifier) buildMessage(batch template.Data) (AttachmentMessage, error) {
+	text, ok, err := a.template.Render(batch)
+	if err != nil {
+		return AttachmentMessage{}, err
+	}
+	if ok {
+		return AttachmentMessage{Text: text}, nil
+	}
+
+	firing, resolved := utils.SplitByStatus(batch)
+	now := time.Now()
+
+	message := AttachmentMessage{Text: alertSummary(len(firing), len(resolved))}
+	for _, alert := range firing {
+		message.Attachments = append(message.Attachments, a.attachment(batch, alert, "firing", now))
+	}
+	for _, alert := range resolved {
+		message.Attachments = append(message.Attachments, a.attachment(batch, alert, "resolved", now))
+	}
+	return message, nil
+}
+
+// attachment 生成单个告警的附件：标题链接到图表，正文为摘要、描述和静默链接，时间等信息为并排字段
+func (a *AttachmentNotifier) attachment(batch template.Data, alert template.Alert, status string, now time.Time) Attachment {
+	severity := alert.Labels["severity"]
+
+	title := fmt.Sprintf("[%s] %s", utils.MapSeverity(severity), alert.Labels["alertname"])
+	fields := []AttachmentField{
+		{Title: "实例", Value: alert.Labels["instance"], Short: true},
+		{Title: "触发时间", Value: a.timeFormat.Format(alert.StartsAt), Short: true},
+	}
+	if status == "resolved" {
+		title = fmt.Sprintf("[已恢复] %s", alert.Labels["alertname"])
+		fields = append(fields,
+			AttachmentField{Title: "恢复时间", Value: a.timeFormat.Format(alert.EndsAt), Short: true},
+			AttachmentField{Title: "持续时间", Value: utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now)), Short: true})
+	} else {
+		fields = append(fields, AttachmentField{Title: "已持续", Value: utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now)), Short: true})
+	}
+
+	var text []string
+	if summary := alert.Annotations["summary"]; summary != "" {
+		text = append(text, summary)
+	}
+	if desc := alert.Annotations["description"]; desc != "" && status == "firing" {
+		text = append(text, desc)
+	}
+	// 已恢复的告警无需静默
+	if silence := utils.SilenceURL(batch.ExternalURL, alert.Labels); status == "firing" && silence != "" {
+		text = append(text, "[静默]("+silence+")")
+	}
+
+	return Attachment{
+		Fallback:  title,
+		Color:     utils.SeverityHexColor(severity, status),
+		Title:     title,
+		TitleLink: alert.GeneratorURL,
+		Text:      strings.Join(text, "\n"),
+		Fields:    fields,
+	}
+}
+
+// checkRocketChatResponse 解析 Rocket.Chat 响应体中的 success 和 error
+func (a *AttachmentNotifier) checkRocketChatResponse(body []byte) error {
+	var resp rocketChatResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Printf("[%s] 解析响应体失败，忽略错误码检查: %v", a.name, err)
+		return nil
+	}
+	if resp.Success != nil && !*resp.Success {
+		return newVendorError(a.name, 0, resp.Error, false)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestMattermostPayload(t *testing.T) {
+	server := newCaptureServer(t, "ok")
+	n, err := NewMattermostNotifier("mattermost", Config{WebhookURL: server.URL + "/hooks/xyz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := template.Data{
+		Status:      "firing",
+		ExternalURL: "http://alertmanager:9093",
+		Alerts: template.Alerts{
+			{Status: "firing", Labels: template.KV{"alertname": "HighCPU", "instance": "node-1", "severity": "emergency"},
+				Annotations: template.KV{"summary": "CPU 持续升高", "description": "超过 90%"}, GeneratorURL: "http://prometheus/graph"},
+			{Status: "resolved", Labels: template.KV{"alertname": "DiskFull", "severity": "warning"},
+				Annotations: template.KV{"summary": "磁盘空间已释放", "description": "不应出现"}},
+		},
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Path != "/hooks/xyz" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	var msg AttachmentMessage
+	if err := json.Unmarshal(requests[0].Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg.Text, "1 个告警中，1 个已恢复") || len(msg.Attachments) != 2 {
+		t.Fatalf("message = %+v", msg)
+	}
+
+	firing, resolved := msg.Attachments[0], msg.Attachments[1]
+	if firing.Title != "[P0] HighCPU" || firing.Fallback != firing.Title || firing.TitleLink != "http://prometheus/graph" || firing.Color != "#D00000" {
+		t.Errorf("firing attachment = %+v", firing)
+	}
+	if !strings.HasPrefix(firing.Text, "CPU 持续升高\n超过 90%\n[静默](http://alertmanager:9093/#/silences/new") {
+		t.Errorf("firing text = %q", firing.Text)
+	}
+	if len(firing.Fields) != 3 || firing.Fields[0] != (AttachmentField{Title: "实例", Value: "node-1", Short: true}) || firing.Fields[2].Title != "已持续" {
+		t.Errorf("firing fields = %+v", firing.Fields)
+	}
+	// 已恢复的告警只保留摘要，没有静默链接
+	if resolved.Title != "[已恢复] DiskFull" || resolved.Color != "#2EB67D" || resolved.Text != "磁盘空间已释放" {
+		t.Errorf("resolved attachment = %+v", resolved)
+	}
+	if len(resolved.Fields) != 4 || resolved.Fields[2].Title != "恢复时间" || resolved.Fields[3].Title != "持续时间" {
+		t.Errorf("resolved fields = %+v", resolved.Fields)
+	}
+}
+
+func TestAttachmentNotifierSplitsByLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		factory Factory
+		max     int
+	}{
+		{"mattermost", NewMattermostNotifier, mattermostMaxLength},
+		{"rocketchat", NewRocketChatNotifier, rocketChatMaxLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := tt.factory(tt.name, Config{WebhookURL: "http://127.0.0.1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data := testAlerts("a", "b", "c", "d", "e", "f", "g", "h", "i", "j")
+			for i := range data.Alerts {
+				data.Alerts[i].Annotations = template.KV{"description": strings.Repeat("告", 1500)}
+			}
+			messages, err := n.Format(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) < 2 {
+				t.Fatalf("messages = %d, want alerts split by message length", len(messages))
+			}
+			total := 0
+			for i, message := range messages {
+				body, _ := json.Marshal(message.Payload)
+				if chars := utf8.RuneCount(body); chars > tt.max {
+					t.Errorf("message %d = %d chars, exceeds %d", i, chars, tt.max)
+				}
+				total += len(message.Payload.(AttachmentMessage).Attachments)
+			}
+			if total != len(data.Alerts) {
+				t.Errorf("attachments = %d, want one per alert", total)
+			}
+		})
+	}
+}
+
+func TestRocketChatErrorResponse(t *testing.T) {
+	server := newCaptureServer(t, `{"success":false,"error":"Invalid integration"}`)
+	n, err := NewRocketChatNotifier("rocketchat", Config{WebhookURL: server.URL + "/hooks/abc/def"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, err = Dispatch(context.Background(), n, testAlerts("HighCPU"), nil)
+	var vendorErr *VendorError
+	if !errors.As(err, &vendorErr) || vendorErr.Message != "Invalid integration" || IsRetryable(err) {
+		t.Fatalf("err = %v, want non-retryable VendorError", err)
+	}
+	if len(server.Requests()) != 1 {
+		t.Errorf("requests = %d, want no retry", len(server.Requests()))
+	}
+}
+
+func TestRocketChatSuccessResponse(t *testing.T) {
+	server := newCaptureServer(t, `{"success":true}`)
+	n, err := NewRocketChatNotifier("rocketchat", Config{WebhookURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, testAlerts("HighCPU"), nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+}
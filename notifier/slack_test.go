@@ -0,0 +1,87 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestSlackPayload(t *testing.T) {
+	server := newCaptureServer(t, "ok")
+	n, err := NewSlackNotifier("slack", Config{WebhookURL: server.URL + "/services/T0/B0/xyz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := template.Data{
+		Status:      "firing",
+		ExternalURL: "http://alertmanager:9093",
+		Alerts: template.Alerts{
+			{Status: "firing", Labels: template.KV{"alertname": "HighCPU", "instance": "<node-1>", "severity": "critical"},
+				Annotations: template.KV{"summary": "CPU > 90% & rising"}, GeneratorURL: "http://prometheus/graph"},
+			{Status: "resolved", Labels: template.KV{"alertname": "DiskFull", "severity": "warning"}},
+		},
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Path != "/services/T0/B0/xyz" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	var msg SlackMessage
+	if err := json.Unmarshal(requests[0].Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg.Text, "1 个告警中，1 个已恢复") || len(msg.Attachments) != 2 {
+		t.Fatalf("message = %+v", msg)
+	}
+
+	firing, resolved := msg.Attachments[0], msg.Attachments[1]
+	if firing.Color != "#FF7F0E" || resolved.Color != "#2EB67D" {
+		t.Errorf("colors = %s, %s", firing.Color, resolved.Color)
+	}
+	// mrkdwn 控制字符经过转义
+	text := firing.Blocks[0].Text.Text
+	for _, want := range []string{"*[P1] HighCPU*", "&lt;node-1&gt;", "CPU &gt; 90% &amp; rising"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("firing text missing %q:\n%s", want, text)
+		}
+	}
+	buttons := firing.Blocks[1].Elements
+	if len(buttons) != 2 || buttons[0].URL != "http://prometheus/graph" || !strings.HasPrefix(buttons[1].URL, "http://alertmanager:9093/#/silences/new") {
+		t.Errorf("buttons = %+v", buttons)
+	}
+	// 已恢复的告警没有静默按钮
+	if len(resolved.Blocks) != 1 || !strings.Contains(resolved.Blocks[0].Text.Text, "[已恢复] DiskFull") {
+		t.Errorf("resolved blocks = %+v", resolved.Blocks)
+	}
+}
+
+func TestSlackSplitsByAttachmentCount(t *testing.T) {
+	n, err := NewSlackNotifier("slack", Config{WebhookURL: "http://127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 45)
+	for i := range names {
+		names[i] = "alert-" + string(rune('a'+i%26))
+	}
+	messages, err := n.Format(testAlerts(names...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("messages = %d, want 3", len(messages))
+	}
+	for _, message := range messages {
+		if got := len(message.Payload.(SlackMessage).Attachments); got > slackMaxAttachments {
+			t.Errorf("attachments = %d, exceeds %d", got, slackMaxAttachments)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// smsParamMaxLength 模板参数值的最大长度，阿里云短信变量默认不超过35个字符，超出时截断
+const smsParamMaxLength = 35
+
+// 每个号码默认的发送频率上限：每小时最多5条
+const (
+	smsDefaultMaxPerNumber = 5
+	smsDefaultInterval     = 3600
+)
+
+// smsCountParam 模板参数映射中表示告警中告警数量的特殊值
+const smsCountParam = "$count"
+
+// smsAnnotationPrefix 模板参数映射中表示注解的前缀，如 annotations.summary
+const smsAnnotationPrefix = "annotations."
+
+// SMSConfig 短信和语音电话网关接收者配置，网关地址使用 webhook_url，签名密钥使用 secret
+type SMSConfig struct {
+	AccessKeyID string `yaml:"access_key_id"`
+	// 短信签名，语音电话可不配置
+	SignName string `yaml:"sign_name"`
+	// 短信模板或语音文本转语音模板 ID
+	TemplateID string `yaml:"template_id"`
+	// 模板参数名 -> 告警标签名；annotations. 前缀表示注解，$count 表示告警中的告警数量
+	TemplateParams map[string]string `yaml:"template_params"`
+	// 接收短信或电话的手机号，不同路由的接收者可配置不同的号码
+	Phones []string `yaml:"phones"`
+	// 每个号码的发送频率上限
+	RateLimit SMSRateLimit `yaml:"rate_limit"`
+}
+
+// SMSRateLimit 每个号码在 interval 秒内最多发送 max_per_number 条，超出的消息丢弃并作为永久错误返回
+// 计数按号码在全部短信和语音接收者之间共享，只统计发送成功的消息；计数保存在内存中，进程重启后清零
+type SMSRateLimit struct {
+	MaxPerNumber int `yaml:"max_per_number"`
+	Interval     int `yaml:"interval"`
+}
+
+// SMSMessage 发送到网关的请求体，每个号码一条
+type SMSMessage struct {
+	// sms 或 voice
+	Channel        string            `json:"channel"`
+	PhoneNumber    string            `json:"phone_number"`
+	SignName       string            `json:"sign_name,omitempty"`
+	TemplateID     string            `json:"template_id"`
+	TemplateParams map[string]string `json:"template_params"`
+}
+
+// SMSNotifier 短信或语音电话网关通知器，用于 P0 告警升级，只通知告警中的告警
+type SMSNotifier struct {
+	name           string
+	channel        string
+	gatewayURL     string
+	requestURI     string
+	accessKeyID    string
+	secret         string
+	signName       string
+	templateID     string
+	templateParams map[string]string
+	phones         []string
+	maxPerNumber   int
+	window         time.Duration
+	limiter        *smsLimiter
+	sender         *Sender
+}
+
+// smsResponse 网关响应体，code 为 OK、0 或 200 时表示成功，兼容字符串和数字
+type smsResponse struct {
+	Code    json.RawMessage `json:"code"`
+	Message string          `json:"message"`
+}
+
+// smsRateLimiter 进程内全部短信和语音接收者共享的号码发送记录，同一号码出现在多个接收者中时合并计数
+var smsRateLimiter = newSMSLimiter()
+
+func init() {
+	Register("sms", NewSMSNotifier)
+	Register("voice", NewVoiceNotifier)
+}
+
+// NewSMSNotifier 创建短信通知器
+func NewSMSNotifier(name string, cfg Config) (Notifier, error) {
+	return newSMSNotifier(name, "sms", cfg)
+}
+
+// NewVoiceNotifier 创建语音电话通知器，与短信使用相同的网关配置，模板为语音模板
+func NewVoiceNotifier(name string, cfg Config) (Notifier, error) {
+	return newSMSNotifier(name, "voice", cfg)
+}
+
+func newSMSNotifier(name, channel string, cfg Config) (*SMSNotifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+	if cfg.AccessKeyID == "" || cfg.Secret == "" {
+		return nil, fmt.Errorf("接收者 %s 的 access_key_id 和 secret 必须配置", name)
+	}
+	if cfg.TemplateID == "" {
+		return nil, fmt.Errorf("接收者 %s 的 template_id 未配置", name)
+	}
+	if len(cfg.Phones) == 0 {
+		return nil, fmt.Errorf("接收者 %s 的 phones 未配置", name)
+	}
+
+	gatewayURL, err := url.Parse(cfg.WebhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL无效: %w", name, stripURL(err))
+	}
+
+	secret, err := resolveSecret(cfg.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 secret 读取失败: %w", name, err)
+	}
+
+	maxPerNumber := cfg.RateLimit.MaxPerNumber
+	if maxPerNumber == 0 {
+		maxPerNumber = smsDefaultMaxPerNumber
+	}
+	interval := cfg.RateLimit.Interval
+	if interval == 0 {
+		interval = smsDefaultInterval
+	}
+	if maxPerNumber < 0 || interval < 0 {
+		return nil, fmt.Errorf("接收者 %s 的 rate_limit 不能为负数", name)
+	}
+
+	n := &SMSNotifier{
+		name:           name,
+		channel:        channel,
+		gatewayURL:     cfg.WebhookURL,
+		requestURI:     gatewayURL.RequestURI(),
+		accessKeyID:    cfg.AccessKeyID,
+		secret:         secret,
+		signName:       cfg.SignName,
+		templateID:     cfg.TemplateID,
+		templateParams: cfg.TemplateParams,
+		phones:         cfg.Phones,
+		maxPerNumber:   maxPerNumber,
+		window:         time.Duration(interval) * time.Second,
+		limiter:        smsRateLimiter,
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkResponse)
+	return n, nil
+}
+
+func (s *SMSNotifier) Name() string {
+	return s.name
+}
+
+// MaxMessageSize 短信内容由网关模板决定，不分批
+func (s *SMSNotifier) MaxMessageSize() int {
+	return 0
+}
+
+// Format 每个号码生成一条消息，模板参数取自告警中级别最高的告警；全部已恢复时不发送
+func (s *SMSNotifier) Format(data template.Data) ([]Message, error) {
+	firing, _ := utils.SplitByStatus(data)
+	if len(firing) == 0 {
+		log.Printf("[%s] 没有告警中的告警，不发送%s", s.name, s.channelName())
+		return nil, nil
+	}
+
+	params := s.params(firing)
+	messages := make([]Message, 0, len(s.phones))
+	for _, phone := range s.phones {
+		messages = append(messages, Message{Alerts: firing, Payload: SMSMessage{
+			Channel:        s.channel,
+			PhoneNumber:    phone,
+			SignName:       s.signName,
+			TemplateID:     s.templateID,
+			TemplateParams: params,
+		}})
+	}
+	return messages, nil
+}
+
+// Send 超过号码发送频率上限时丢弃消息并返回不可重试的错误，使投递结果和审计记录体现丢弃
+// 否则签名后发送，每次重试重新签名，发送失败时不计入频率统计
+func (s *SMSNotifier) Send(ctx context.Context, message interface{}) error {
+	msg, ok := message.(SMSMessage)
+	if !ok {
+		return &SendError{Err: fmt.Errorf("[%s] 不支持的消息类型 %T", s.name, message)}
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return &SendError{Err: fmt.Errorf("[%s] JSON编码失败: %w", s.name, err)}
+	}
+
+	// 发送前占用名额，避免多个接收者并发发送时超过上限，发送失败时归还
+	reserved, ok := s.limiter.Reserve(msg.PhoneNumber, s.maxPerNumber, s.window, time.Now())
+	if !ok {
+		return &SendError{Err: fmt.Errorf("[%s] 号码 %s 已达到发送频率上限（%d 条/%s），丢弃本次%s",
+			s.name, maskPhone(msg.PhoneNumber), s.maxPerNumber, s.window, s.channelName())}
+	}
+	err = s.sender.Retry(ctx, func(ctx context.Context) error {
+		return s.sender.do(ctx, http.MethodPost, s.gatewayURL, s.signedHeader(body, time.Now()), body)
+	})
+	if err != nil {
+		s.limiter.Release(msg.PhoneNumber, reserved)
+		return err
+	}
+	return nil
+}
+
+// TestConnection 短信和电话按条计费且会打扰值班人员，因此跳过连通性测试
+func (s *SMSNotifier) TestConnection() error {
+	log.Printf("[%s] %s网关不发送连通性测试消息", s.name, s.channelName())
+	return nil
+}
+
+func (s *SMSNotifier) channelName() string {
+	if s.channel == "voice" {
+		return "语音电话"
+	}
+	return "短信"
+}
+
+// params 按 template_params 映射生成模板参数，取值来自级别最高的告警中的告警，超长时截断
+func (s *SMSNotifier) params(firing []template.Alert) map[string]string {
+	severity := utils.HighestSeverity(firing)
+	alert := firing[0]
+	for _, a := range firing {
+		if a.Labels["severity"] == severity {
+			alert = a
+			break
+		}
+	}
+
+	params := make(map[string]string, len(s.templateParams))
+	for param, source := range s.templateParams {
+		var value string
+		switch {
+		case source == smsCountParam:
+			value = strconv.Itoa(len(firing))
+		case strings.HasPrefix(source, smsAnnotationPrefix):
+			value = alert.Annotations[strings.TrimPrefix(source, smsAnnotationPrefix)]
+		default:
+			value = alert.Labels[source]
+		}
+		params[param] = utils.Truncate(smsParamMaxLength, value)
+	}
+	return params
+}
+
+// signedHeader 生成请求签名头：对 "方法\n路径\n时间戳\n随机串\n请求体 SHA256" 计算 HMAC-SHA256 后 Base64 编码
+func (s *SMSNotifier) signedHeader(body []byte, now time.Time) http.Header {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	nonce := smsNonce()
+	bodyHash := sha256.Sum256(body)
+
+	stringToSign := strings.Join([]string{http.MethodPost, s.requestURI, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	mac.Write([]byte(stringToSign))
+
+	return http.Header{
+		"Content-Type":    {"application/json"},
+		"X-Access-Key-Id": {s.accessKeyID},
+		"X-Timestamp":     {timestamp},
+		"X-Nonce":         {nonce},
+		"X-Signature":     {base64.StdEncoding.EncodeToString(mac.Sum(nil))},
+	}
+}
+
+// checkResponse 解析网关响应体中的 code 和 message，业务错误（如余额不足、模板不存在）不重试
+func (s *SMSNotifier) checkResponse(body []byte) error {
+	var resp smsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Printf("[%s] 解析响应体失败，忽略错误码检查: %v", s.name, err)
+		return nil
+	}
+	if len(resp.Code) == 0 {
+		return nil
+	}
+
+	code := strings.Trim(string(resp.Code), `"`)
+	switch strings.ToUpper(code) {
+	case "OK", "0", "200":
+		return nil
+	}
+	if numeric, err := strconv.Atoi(code); err == nil {
+		return newVendorError(s.name, numeric, resp.Message, false)
+	}
+	return newVendorError(s.name, 0, code+": "+resp.Message, false)
+}
+
+// smsNonce 生成请求签名使用的随机串
+func smsNonce() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// maskPhone 日志中隐藏手机号中间四位
+func maskPhone(phone string) string {
+	if len(phone) < 11 {
+		return phone
+	}
+	return phone[:len(phone)-8] + "****" + phone[len(phone)-4:]
+}
+
+// smsLimiter 按号码记录滑动窗口内发送成功的时间，各接收者按自己的 rate_limit 检查同一份记录
+type smsLimiter struct {
+	mu sync.Mutex
+	// 已使用的最长窗口，早于该窗口的记录不再需要
+	retention time.Duration
+	sent      map[string][]time.Time
+}
+
+func newSMSLimiter() *smsLimiter {
+	return &smsLimiter{sent: make(map[string][]time.Time)}
+}
+
+// Reserve 号码在 window 内的发送次数未达 max 时占用一个名额并返回占用时间，已达上限时返回 false
+func (l *smsLimiter) Reserve(phone string, max int, window time.Duration, now time.Time) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if window > l.retention {
+		l.retention = window
+	}
+	count := 0
+	for _, t := range l.recent(phone, now) {
+		if now.Sub(t) < window {
+			count++
+		}
+	}
+	if count >= max {
+		return time.Time{}, false
+	}
+	l.sent[phone] = append(l.sent[phone], now)
+	return now, true
+}
+
+// Release 发送失败时归还 Reserve 占用的名额
+func (l *smsLimiter) Release(phone string, reserved time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sent := l.sent[phone]
+	for i, t := range sent {
+		if t.Equal(reserved) {
+			l.sent[phone] = append(sent[:i], sent[i+1:]...)
+			return
+		}
+	}
+}
+
+// recent 清理超过最长窗口的记录，返回剩余的发送时间，调用方需持有锁
+func (l *smsLimiter) recent(phone string, now time.Time) []time.Time {
+	recent := l.sent[phone][:0]
+	for _, t := range l.sent[phone] {
+		if now.Sub(t) < l.retention {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		delete(l.sent, phone)
+		return nil
+	}
+	l.sent[phone] = recent
+	return recent
+}
@@ -0,0 +1,261 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// fakeSMSGateway 短信网关模拟服务，按请求头校验签名，fail 为 true 时返回 HTTP 500
+type fakeSMSGateway struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	fail     bool
+	messages []SMSMessage
+}
+
+func (g *fakeSMSGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if err := g.verify(r, body); err != "" {
+		g.t.Errorf("invalid signature: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if g.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var msg SMSMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		g.t.Errorf("decode body: %v", err)
+	}
+	g.messages = append(g.messages, msg)
+	w.Write([]byte(`{"code":"OK","message":"OK"}`))
+}
+
+// verify 按网关文档的签名规则重新计算签名
+func (g *fakeSMSGateway) verify(r *http.Request, body []byte) string {
+	if r.Header.Get("X-Access-Key-Id") != "AKTEST" {
+		return "access key id = " + r.Header.Get("X-Access-Key-Id")
+	}
+	timestamp := r.Header.Get("X-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > time.Minute {
+		return "timestamp = " + timestamp
+	}
+	nonce := r.Header.Get("X-Nonce")
+	if nonce == "" {
+		return "missing nonce"
+	}
+
+	bodyHash := sha256.Sum256(body)
+	stringToSign := strings.Join([]string{r.Method, r.URL.RequestURI(), timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+	mac := hmac.New(sha256.New, []byte(g.secret))
+	mac.Write([]byte(stringToSign))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Signature") != want {
+		return "signature = " + r.Header.Get("X-Signature") + ", want " + want
+	}
+	return ""
+}
+
+// resetSMSLimiter 每个用例使用独立的号码发送记录
+func resetSMSLimiter(t *testing.T) {
+	t.Helper()
+	previous := smsRateLimiter
+	smsRateLimiter = newSMSLimiter()
+	t.Cleanup(func() { smsRateLimiter = previous })
+}
+
+func newTestSMS(t *testing.T, gateway *fakeSMSGateway, maxPerNumber int) *SMSNotifier {
+	t.Helper()
+	return newTestGatewayNotifier(t, NewSMSNotifier, "p0-sms", gateway, maxPerNumber)
+}
+
+func newTestGatewayNotifier(t *testing.T, factory Factory, name string, gateway *fakeSMSGateway, maxPerNumber int) *SMSNotifier {
+	t.Helper()
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+
+	maxRetries := 0
+	n, err := factory(name, Config{
+		WebhookURL: server.URL + "/v1/sms/send?region=cn",
+		Secret:     gateway.secret,
+		Delivery:   DeliveryConfig{MaxRetries: &maxRetries},
+		SMSConfig: SMSConfig{
+			AccessKeyID: "AKTEST",
+			SignName:    "运维告警",
+			TemplateID:  "SMS_123",
+			TemplateParams: map[string]string{
+				"alert":   "alertname",
+				"summary": "annotations.summary",
+				"count":   "$count",
+			},
+			Phones:    []string{"13800000000", "13900000000"},
+			RateLimit: SMSRateLimit{MaxPerNumber: maxPerNumber},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.(*SMSNotifier)
+}
+
+func smsAlerts() template.Data {
+	return template.Data{
+		Status: "firing",
+		Alerts: template.Alerts{
+			{Status: "firing", Labels: template.KV{"alertname": "DiskFull", "severity": "warning"}},
+			{Status: "firing", Labels: template.KV{"alertname": "NodeDown", "severity": "emergency"},
+				Annotations: template.KV{"summary": strings.Repeat("节点", 30)}},
+			{Status: "resolved", Labels: template.KV{"alertname": "Recovered", "severity": "emergency"}},
+		},
+	}
+}
+
+func TestSMSSignedRequest(t *testing.T) {
+	resetSMSLimiter(t)
+	gateway := &fakeSMSGateway{t: t, secret: "gateway-secret"}
+	n := newTestSMS(t, gateway, 5)
+
+	delivered, sent, total, err := Dispatch(context.Background(), n, smsAlerts(), nil)
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if sent != 2 || total != 2 || len(delivered) != 3 {
+		t.Fatalf("sent/total/delivered = %d/%d/%d, want 2/2/3", sent, total, len(delivered))
+	}
+
+	msg := gateway.messages[0]
+	if msg.Channel != "sms" || msg.PhoneNumber != "13800000000" || msg.SignName != "运维告警" || msg.TemplateID != "SMS_123" {
+		t.Errorf("message = %+v", msg)
+	}
+	// 参数取自级别最高的告警中的告警，只统计告警中的告警，超长时截断
+	want := map[string]string{"alert": "NodeDown", "count": "2"}
+	for key, value := range want {
+		if msg.TemplateParams[key] != value {
+			t.Errorf("param %s = %q, want %q", key, msg.TemplateParams[key], value)
+		}
+	}
+	if summary := []rune(msg.TemplateParams["summary"]); len(summary) != smsParamMaxLength {
+		t.Errorf("summary length = %d, want %d", len(summary), smsParamMaxLength)
+	}
+}
+
+func TestSMSRateCapReportsDrop(t *testing.T) {
+	resetSMSLimiter(t)
+	gateway := &fakeSMSGateway{t: t, secret: "gateway-secret"}
+	n := newTestSMS(t, gateway, 1)
+	message := SMSMessage{Channel: "sms", PhoneNumber: "13800000000", TemplateID: "SMS_123"}
+
+	if err := n.Send(context.Background(), message); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	err := n.Send(context.Background(), message)
+	if err == nil {
+		t.Fatal("send over the rate cap returned nil")
+	}
+	if IsRetryable(err) {
+		t.Errorf("rate cap error should not be retryable: %v", err)
+	}
+	if strings.Contains(err.Error(), "13800000000") {
+		t.Errorf("error leaks full phone number: %v", err)
+	}
+	if len(gateway.messages) != 1 {
+		t.Errorf("gateway received %d messages, want 1", len(gateway.messages))
+	}
+
+	// 其他号码不受影响
+	message.PhoneNumber = "13900000000"
+	if err := n.Send(context.Background(), message); err != nil {
+		t.Errorf("send to another number: %v", err)
+	}
+}
+
+func TestSMSFailedSendDoesNotUseRateSlot(t *testing.T) {
+	resetSMSLimiter(t)
+	gateway := &fakeSMSGateway{t: t, secret: "gateway-secret", fail: true}
+	n := newTestSMS(t, gateway, 1)
+	message := SMSMessage{Channel: "sms", PhoneNumber: "13800000000", TemplateID: "SMS_123"}
+
+	if err := n.Send(context.Background(), message); err == nil || !IsRetryable(err) {
+		t.Fatalf("send to failing gateway = %v, want retryable error", err)
+	}
+
+	gateway.mu.Lock()
+	gateway.fail = false
+	gateway.mu.Unlock()
+	if err := n.Send(context.Background(), message); err != nil {
+		t.Fatalf("send after gateway recovered: %v", err)
+	}
+}
+
+func TestSMSRateCapSharedAcrossReceivers(t *testing.T) {
+	resetSMSLimiter(t)
+	gateway := &fakeSMSGateway{t: t, secret: "gateway-secret"}
+	sms := newTestGatewayNotifier(t, NewSMSNotifier, "p0-sms", gateway, 2)
+	voice := newTestGatewayNotifier(t, NewVoiceNotifier, "p0-voice", gateway, 2)
+	message := SMSMessage{PhoneNumber: "13800000000", TemplateID: "SMS_123"}
+
+	if err := sms.Send(context.Background(), message); err != nil {
+		t.Fatalf("sms send: %v", err)
+	}
+	if err := voice.Send(context.Background(), message); err != nil {
+		t.Fatalf("voice send: %v", err)
+	}
+	// 同一号码在两个接收者中合计已发送2条，任一接收者都不能再发送
+	if err := sms.Send(context.Background(), message); err == nil {
+		t.Error("sms send over the shared cap returned nil")
+	}
+	if err := voice.Send(context.Background(), message); err == nil {
+		t.Error("voice send over the shared cap returned nil")
+	}
+	if len(gateway.messages) != 2 {
+		t.Errorf("gateway received %d messages, want 2", len(gateway.messages))
+	}
+}
+
+func TestSMSLimiterWindow(t *testing.T) {
+	limiter := newSMSLimiter()
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if _, ok := limiter.Reserve("138", 2, time.Hour, now); !ok {
+			t.Fatalf("send %d not allowed", i)
+		}
+	}
+	if _, ok := limiter.Reserve("138", 2, time.Hour, now.Add(59*time.Minute)); ok {
+		t.Error("third send within the window allowed")
+	}
+	// 窗口较短的接收者只统计自己窗口内的发送
+	if _, ok := limiter.Reserve("138", 2, time.Minute, now.Add(2*time.Minute)); !ok {
+		t.Error("send outside the shorter window not allowed")
+	}
+
+	reserved, ok := limiter.Reserve("139", 1, time.Hour, now)
+	if !ok {
+		t.Fatal("first send not allowed")
+	}
+	limiter.Release("139", reserved)
+	if _, ok := limiter.Reserve("139", 1, time.Hour, now); !ok {
+		t.Error("released slot not reusable")
+	}
+}
@@ -0,0 +1,254 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// teamsMaxLength Teams 单条消息上限约28KB，按 JSON 长度留一些安全边界
+const teamsMaxLength = 25000
+
+// TeamsMessage Teams Workflows / Incoming Webhook 消息结构，内容为一张 Adaptive Card
+type TeamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []TeamsAttachment `json:"attachments"`
+}
+
+type TeamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     AdaptiveCard `json:"content"`
+}
+
+type AdaptiveCard struct {
+	Schema  string            `json:"$schema"`
+	Type    string            `json:"type"`
+	Version string            `json:"version"`
+	Body    []AdaptiveElement `json:"body"`
+	MSTeams map[string]string `json:"msteams,omitempty"`
+}
+
+// AdaptiveElement 卡片元素，TextBlock 使用 Text，Container 使用 Items，FactSet 使用 Facts，ActionSet 使用 Actions
+type AdaptiveElement struct {
+	Type      string            `json:"type"`
+	Text      string            `json:"text,omitempty"`
+	Weight    string            `json:"weight,omitempty"`
+	Size      string            `json:"size,omitempty"`
+	Color     string            `json:"color,omitempty"`
+	Wrap      bool              `json:"wrap,omitempty"`
+	Style     string            `json:"style,omitempty"`
+	Bleed     bool              `json:"bleed,omitempty"`
+	Separator bool              `json:"separator,omitempty"`
+	Items     []AdaptiveElement `json:"items,omitempty"`
+	Facts     []AdaptiveFact    `json:"facts,omitempty"`
+	Actions   []AdaptiveAction  `json:"actions,omitempty"`
+}
+
+type AdaptiveFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type AdaptiveAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// TeamsNotifier Microsoft Teams 通知器，支持 Workflows 和旧版 Incoming Webhook 地址
+type TeamsNotifier struct {
+	name       string
+	webhookURL string
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+func init() {
+	Register("teams", NewTeamsNotifier)
+}
+
+// NewTeamsNotifier 创建 Teams 通知器
+func NewTeamsNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+	n := &TeamsNotifier{
+		name:       name,
+		webhookURL: cfg.WebhookURL,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkResponse)
+	return n, nil
+}
+
+func (t *TeamsNotifier) Name() string {
+	return t.name
+}
+
+func (t *TeamsNotifier) MaxMessageSize() int {
+	return teamsMaxLength
+}
+
+func (t *TeamsNotifier) Format(data template.Data) ([]Message, error) {
+	format := newPayloadFormatter(t.buildMessage, jsonLength[TeamsMessage])
+	return format.Messages(utils.SplitAlertsFunc(data, t.MaxMessageSize(), format.Size))
+}
+
+func (t *TeamsNotifier) Send(ctx context.Context, message interface{}) error {
+	return t.sender.SendAlert(ctx, t.webhookURL, message)
+}
+
+func (t *TeamsNotifier) TestConnection() error {
+	return t.sender.SendTestMessage(t.webhookURL, newTeamsMessage([]AdaptiveElement{
+		{Type: "TextBlock", Text: "Teams连通性测试", Wrap: true},
+	}))
+}
+
+// checkResponse 旧版 Incoming Webhook 在 HTTP 200 的响应体中返回投递失败信息，
+// 如 "Webhook message delivery failed with error: Microsoft Teams endpoint returned HTTP error 429"，
+// 以其中的 HTTP 状态码作为错误码，429 和 5xx 视为可重试
+func (t *TeamsNotifier) checkResponse(body []byte) error {
+	text := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(text, "Webhook message delivery failed") {
+		return nil
+	}
+
+	code := 0
+	if _, after, ok := strings.Cut(text, "HTTP error "); ok {
+		digits := after[:len(after)-len(strings.TrimLeft(after, "0123456789"))]
+		code, _ = strconv.Atoi(digits)
+	}
+	return newVendorError(t.name, code, text, code == http.StatusTooManyRequests || code >= 500)
+}
+
+// buildMessage 配置了自定义模板时以模板渲染结果作为卡片文本，否则每个告警生成一个按级别着色的区块
+func (t *TeamsNotifier) buildMessage(batch template.Data) (TeamsMessage, error) {
+	text, ok, err := t.template.Render(batch)
+	if err != nil {
+		return TeamsMessage{}, err
+	}
+	if ok {
+		return newTeamsMessage([]AdaptiveElement{{Type: "TextBlock", Text: text, Wrap: true}}), nil
+	}
+
+	firing, resolved := utils.SplitByStatus(batch)
+	now := time.Now()
+
+	body := []AdaptiveElement{{
+		Type:   "TextBlock",
+		Text:   alertSummary(len(firing), len(resolved)),
+		Weight: "Bolder",
+		Size:   "Medium",
+		Wrap:   true,
+	}}
+	for _, alert := range firing {
+		body = append(body, t.alertContainer(batch, alert, "firing", now))
+	}
+	for _, alert := range resolved {
+		body = append(body, t.alertContainer(batch, alert, "resolved", now))
+	}
+	return newTeamsMessage(body), nil
+}
+
+// newTeamsMessage 将卡片内容包装为 Teams 消息
+func newTeamsMessage(body []AdaptiveElement) TeamsMessage {
+	return TeamsMessage{
+		Type: "message",
+		Attachments: []TeamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: AdaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+				MSTeams: map[string]string{"width": "Full"},
+			},
+		}},
+	}
+}
+
+// alertContainer 生成单个告警的区块：按级别着色的标题、标签和注解事实列表、查看图表和静默链接
+func (t *TeamsNotifier) alertContainer(batch template.Data, alert template.Alert, status string, now time.Time) AdaptiveElement {
+	severity := alert.Labels["severity"]
+	style, color := teamsSeverityStyle(severity, status)
+
+	title := fmt.Sprintf("[%s] %s", utils.MapSeverity(severity), alert.Labels["alertname"])
+	facts := []AdaptiveFact{{Title: "触发时间", Value: t.timeFormat.Format(alert.StartsAt)}}
+	if status == "resolved" {
+		title = fmt.Sprintf("[已恢复] %s", alert.Labels["alertname"])
+		facts = append(facts,
+			AdaptiveFact{Title: "恢复时间", Value: t.timeFormat.Format(alert.EndsAt)},
+			AdaptiveFact{Title: "持续时间", Value: utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now))})
+	} else {
+		facts = append(facts, AdaptiveFact{Title: "已持续", Value: utils.FormatDuration(utils.AlertDuration(alert, batch.Status, now))})
+	}
+
+	items := []AdaptiveElement{
+		{Type: "TextBlock", Text: title, Weight: "Bolder", Color: color, Wrap: true},
+		{Type: "FactSet", Facts: facts},
+	}
+	if len(alert.Labels) > 0 {
+		items = append(items,
+			AdaptiveElement{Type: "TextBlock", Text: "标签", Weight: "Bolder", Separator: true},
+			AdaptiveElement{Type: "FactSet", Facts: teamsFacts(alert.Labels)})
+	}
+	if len(alert.Annotations) > 0 {
+		items = append(items,
+			AdaptiveElement{Type: "TextBlock", Text: "注解", Weight: "Bolder", Separator: true},
+			AdaptiveElement{Type: "FactSet", Facts: teamsFacts(alert.Annotations)})
+	}
+
+	var actions []AdaptiveAction
+	if alert.GeneratorURL != "" {
+		actions = append(actions, AdaptiveAction{Type: "Action.OpenUrl", Title: "查看图表", URL: alert.GeneratorURL})
+	}
+	// 已恢复的告警无需静默
+	if silence := utils.SilenceURL(batch.ExternalURL, alert.Labels); status == "firing" && silence != "" {
+		actions = append(actions, AdaptiveAction{Type: "Action.OpenUrl", Title: "静默", URL: silence})
+	}
+	if len(actions) > 0 {
+		items = append(items, AdaptiveElement{Type: "ActionSet", Actions: actions})
+	}
+
+	return AdaptiveElement{
+		Type:      "Container",
+		Style:     style,
+		Bleed:     true,
+		Separator: true,
+		Items:     items,
+	}
+}
+
+// teamsFacts 将标签或注解按名称排序转换为事实列表
+func teamsFacts(kv template.KV) []AdaptiveFact {
+	facts := make([]AdaptiveFact, 0, len(kv))
+	for _, p := range kv.SortedPairs() {
+		facts = append(facts, AdaptiveFact{Title: p.Name, Value: p.Value})
+	}
+	return facts
+}
+
+// teamsSeverityStyle 返回告警级别对应的区块样式和标题颜色，Adaptive Card 只支持预定义的样式
+func teamsSeverityStyle(severity, status string) (string, string) {
+	if status == "resolved" {
+		return "good", "Good"
+	}
+	switch utils.MapSeverity(severity) {
+	case "P0", "P1":
+		return "attention", "Attention"
+	case "P2":
+		return "warning", "Warning"
+	case "P3":
+		return "accent", "Accent"
+	default:
+		return "emphasis", "Default"
+	}
+}
@@ -0,0 +1,132 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestTeamsAdaptiveCardPayload(t *testing.T) {
+	server := newCaptureServer(t, "1")
+	n, err := NewTeamsNotifier("teams", Config{WebhookURL: server.URL + "/workflows/trigger"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(-time.Hour)
+	data := template.Data{
+		Status:      "firing",
+		ExternalURL: "http://alertmanager:9093",
+		Alerts: template.Alerts{
+			{Status: "firing", Labels: template.KV{"alertname": "HighCPU", "severity": "critical"},
+				Annotations: template.KV{"summary": "CPU high"}, StartsAt: start, GeneratorURL: "http://prometheus/graph"},
+			{Status: "resolved", Labels: template.KV{"alertname": "DiskFull", "severity": "warning"},
+				StartsAt: start, EndsAt: time.Now()},
+		},
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	var msg TeamsMessage
+	if err := json.Unmarshal(requests[0].Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "message" || len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("message = %+v", msg)
+	}
+	card := msg.Attachments[0].Content
+	if card.Type != "AdaptiveCard" || len(card.Body) != 3 {
+		t.Fatalf("card = %+v", card)
+	}
+	if !strings.Contains(card.Body[0].Text, "1 个告警中，1 个已恢复") {
+		t.Errorf("summary = %q", card.Body[0].Text)
+	}
+
+	firing, resolved := card.Body[1], card.Body[2]
+	if firing.Type != "Container" || firing.Style != "attention" || !strings.Contains(firing.Items[0].Text, "HighCPU") {
+		t.Errorf("firing container = %+v", firing)
+	}
+	actions := firing.Items[len(firing.Items)-1].Actions
+	if len(actions) != 2 || actions[0].URL != "http://prometheus/graph" || !strings.HasPrefix(actions[1].URL, "http://alertmanager:9093/#/silences/new") {
+		t.Errorf("firing actions = %+v", actions)
+	}
+	if resolved.Style != "good" || !strings.Contains(resolved.Items[0].Text, "[已恢复] DiskFull") {
+		t.Errorf("resolved container = %+v", resolved)
+	}
+}
+
+func TestTeamsSplitsLargePayloads(t *testing.T) {
+	n, err := NewTeamsNotifier("teams", Config{WebhookURL: "http://127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := testAlerts()
+	for i := 0; i < 60; i++ {
+		data.Alerts = append(data.Alerts, template.Alert{
+			Status:      "firing",
+			Labels:      template.KV{"alertname": "HighCPU", "instance": strings.Repeat("n", 10) + string(rune('a'+i%26))},
+			Annotations: template.KV{"description": strings.Repeat("详细描述", 100)},
+		})
+	}
+	messages, err := n.Format(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) < 2 {
+		t.Fatalf("messages = %d, want payload split into several cards", len(messages))
+	}
+	total := 0
+	for _, message := range messages {
+		if size := jsonLength(message.Payload.(TeamsMessage)); size > teamsMaxLength {
+			t.Errorf("message size %d exceeds %d", size, teamsMaxLength)
+		}
+		total += len(message.Alerts)
+	}
+	if total != len(data.Alerts) {
+		t.Errorf("alerts across messages = %d, want %d", total, len(data.Alerts))
+	}
+}
+
+func TestTeamsTemplateErrorIsReturned(t *testing.T) {
+	firing := texttemplate.Must(texttemplate.New("firing").Parse(`{{ index .Alerts 5 }}`))
+	tmpl, err := utils.NewMessageTemplate(firing, nil, utils.DefaultTimeFormat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewTeamsNotifier("teams", Config{WebhookURL: "http://127.0.0.1", MessageTemplate: tmpl})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messages, err := n.Format(testAlerts("a")); err == nil {
+		t.Fatalf("Format = %v, want template error", messages)
+	}
+}
+
+func TestTeamsLegacyWebhookFailure(t *testing.T) {
+	server := newCaptureServer(t, "Webhook message delivery failed with error: Microsoft Teams endpoint returned HTTP error 429 with ContextId tcid=0")
+	maxRetries := 0
+	n, err := NewTeamsNotifier("teams", Config{WebhookURL: server.URL, Delivery: DeliveryConfig{MaxRetries: &maxRetries}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), newTeamsMessage(nil))
+	var vendorErr *VendorError
+	if !errors.As(err, &vendorErr) || vendorErr.Code != 429 || !IsRetryable(err) {
+		t.Fatalf("err = %v, want retryable vendor error 429", err)
+	}
+}
@@ -0,0 +1,265 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// telegramMaxLength Telegram 单条消息上限4096字符（按 UTF-16 计算）
+const telegramMaxLength = 4096
+
+// telegramAPIURL Telegram Bot API 官方地址
+const telegramAPIURL = "https://api.telegram.org"
+
+// Telegram 支持的消息格式
+const (
+	telegramParseHTML       = "HTML"
+	telegramParseMarkdownV2 = "MarkdownV2"
+)
+
+// TelegramConfig Telegram 接收者配置
+type TelegramConfig struct {
+	// 机器人 token，由 @BotFather 分配，支持 env: 和 file: 前缀
+	BotToken string `yaml:"bot_token"`
+	// 接收消息的群组、频道或用户 ID，可配置多个
+	ChatIDs []string `yaml:"chat_ids"`
+	// 消息格式：HTML（默认）或 MarkdownV2
+	ParseMode string `yaml:"parse_mode"`
+}
+
+// TelegramMessage Telegram sendMessage 请求体，每个 chat_id 单独发送
+type TelegramMessage struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode,omitempty"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+// TelegramNotifier Telegram 机器人通知器
+type TelegramNotifier struct {
+	name       string
+	sendURL    string
+	chatIDs    []string
+	parseMode  string
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+// telegramResponse Telegram Bot API 响应体
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func init() {
+	Register("telegram", NewTelegramNotifier)
+}
+
+// NewTelegramNotifier 创建 Telegram 通知器
+func NewTelegramNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.BotToken == "" {
+		return nil, fmt.Errorf("接收者 %s 的 bot_token 未配置", name)
+	}
+	if len(cfg.ChatIDs) == 0 {
+		return nil, fmt.Errorf("接收者 %s 的 chat_ids 未配置", name)
+	}
+	botToken, err := resolveSecret(cfg.BotToken)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 bot_token 读取失败: %w", name, err)
+	}
+
+	var parseMode string
+	switch strings.ToLower(cfg.ParseMode) {
+	case "", "html":
+		parseMode = telegramParseHTML
+	case "markdownv2":
+		parseMode = telegramParseMarkdownV2
+	default:
+		return nil, fmt.Errorf("接收者 %s 的 parse_mode %s 不受支持，可选: HTML、MarkdownV2", name, cfg.ParseMode)
+	}
+
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = telegramAPIURL
+	}
+
+	n := &TelegramNotifier{
+		name:       name,
+		sendURL:    strings.TrimRight(apiURL, "/") + "/bot" + botToken + "/sendMessage",
+		chatIDs:    cfg.ChatIDs,
+		parseMode:  parseMode,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkResponse)
+	return n, nil
+}
+
+func (t *TelegramNotifier) Name() string {
+	return t.name
+}
+
+func (t *TelegramNotifier) MaxMessageSize() int {
+	return telegramMaxLength
+}
+
+// Format 按4096字符限制分批，每批发送到全部 chat_id
+func (t *TelegramNotifier) Format(data template.Data) ([]Message, error) {
+	format := messageFormatter(t.template, t.formatText, t.timeFormat)
+	batches := utils.SplitAlertsFunc(data, t.MaxMessageSize(), func(batch template.Data) int {
+		return telegramLength(format.Text(batch))
+	})
+
+	messages := make([]Message, 0, len(batches)*len(t.chatIDs))
+	for _, batch := range batches {
+		text := format.Text(batch)
+		parseMode := t.parseMode
+
+		// 单个告警仍然超长时截断，截断可能破坏格式标记，改为纯文本发送
+		if telegramLength(text) > telegramMaxLength {
+			log.Printf("[%s] 单个告警消息超过 %d 字符，截断后以纯文本发送", t.name, telegramMaxLength)
+			// Truncate 按字符截断，一个字符最多占两个 UTF-16 编码单元
+			text = utils.Truncate(telegramMaxLength/2, text)
+			parseMode = ""
+		}
+
+		for _, chatID := range t.chatIDs {
+			messages = append(messages, Message{Alerts: batch.Alerts, Payload: TelegramMessage{
+				ChatID:                chatID,
+				Text:                  text,
+				ParseMode:             parseMode,
+				DisableWebPagePreview: true,
+			}})
+		}
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (t *TelegramNotifier) Send(ctx context.Context, message interface{}) error {
+	return t.sender.SendAlert(ctx, t.sendURL, message)
+}
+
+// TestConnection 向每个 chat_id 发送测试消息
+func (t *TelegramNotifier) TestConnection() error {
+	for _, chatID := range t.chatIDs {
+		err := t.sender.SendTestMessage(t.sendURL, TelegramMessage{
+			ChatID: chatID,
+			Text:   "Telegram连通性测试",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkResponse 解析 Telegram 响应体中的 ok 和 error_code
+func (t *TelegramNotifier) checkResponse(body []byte) error {
+	var resp telegramResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Printf("[%s] 解析响应体失败，忽略错误码检查: %v", t.name, err)
+		return nil
+	}
+	if !resp.OK {
+		return newVendorError(t.name, resp.ErrorCode, resp.Description, resp.ErrorCode == http.StatusTooManyRequests)
+	}
+	return nil
+}
+
+// formatText Telegram 内置消息格式，按 parse_mode 生成 HTML 或 MarkdownV2 文本，标签值等内容均经过转义
+func (t *TelegramNotifier) formatText(data template.Data, tf utils.TimeFormat) string {
+	var builder strings.Builder
+	now := time.Now()
+	firing, resolved := utils.SplitByStatus(data)
+
+	line := func(label, value string) {
+		builder.WriteString(t.bold(t.escape(label+":")) + " " + t.escape(value) + "\n")
+	}
+
+	if len(firing) > 0 {
+		builder.WriteString(t.bold(t.escape("🔥 Prometheus告警通知")) + "\n")
+		for _, alert := range firing {
+			builder.WriteString("\n")
+			line("告警名称", alert.Labels["alertname"])
+			line("级别", utils.MapSeverity(alert.Labels["severity"]))
+			line("实例", alert.Labels["instance"])
+			line("摘要", alert.Annotations["summary"])
+			if desc := alert.Annotations["description"]; desc != "" {
+				line("描述", desc)
+			}
+			line("触发时间", tf.Format(alert.StartsAt))
+			line("已持续", utils.FormatDuration(utils.AlertDuration(alert, data.Status, now)))
+
+			var links []string
+			if alert.GeneratorURL != "" {
+				links = append(links, t.link("查看图表", alert.GeneratorURL))
+			}
+			if silence := utils.SilenceURL(data.ExternalURL, alert.Labels); silence != "" {
+				links = append(links, t.link("静默", silence))
+			}
+			if len(links) > 0 {
+				builder.WriteString(strings.Join(links, " | ") + "\n")
+			}
+		}
+	}
+
+	if len(resolved) > 0 {
+		if len(firing) > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(t.bold(t.escape("✅ Prometheus告警恢复")) + "\n")
+		for _, alert := range resolved {
+			builder.WriteString("\n")
+			line("告警名称", alert.Labels["alertname"])
+			line("实例", alert.Labels["instance"])
+			line("恢复时间", tf.Format(alert.EndsAt))
+			line("持续时间", utils.FormatDuration(utils.AlertDuration(alert, data.Status, now)))
+		}
+	}
+
+	return builder.String()
+}
+
+func (t *TelegramNotifier) escape(s string) string {
+	if t.parseMode == telegramParseMarkdownV2 {
+		return utils.EscapeMarkdownV2(s)
+	}
+	return html.EscapeString(s)
+}
+
+// bold 加粗已转义的文本
+func (t *TelegramNotifier) bold(s string) string {
+	if t.parseMode == telegramParseMarkdownV2 {
+		return "*" + s + "*"
+	}
+	return "<b>" + s + "</b>"
+}
+
+// link 生成超链接，MarkdownV2 中链接地址只需转义 ) 和 \
+func (t *TelegramNotifier) link(text, url string) string {
+	if t.parseMode == telegramParseMarkdownV2 {
+		escapedURL := strings.NewReplacer(`\`, `\\`, ")", `\)`).Replace(url)
+		return "[" + utils.EscapeMarkdownV2(text) + "](" + escapedURL + ")"
+	}
+	return `<a href="` + html.EscapeString(url) + `">` + html.EscapeString(text) + "</a>"
+}
+
+// telegramLength 按 UTF-16 编码单元计算消息长度，与 Telegram 的计算方式一致
+func telegramLength(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
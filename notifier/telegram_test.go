@@ -0,0 +1,127 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func newTestTelegram(t *testing.T, apiURL, parseMode string) *TelegramNotifier {
+	t.Helper()
+	n, err := NewTelegramNotifier("telegram", Config{
+		APIURL:         apiURL,
+		TelegramConfig: TelegramConfig{BotToken: "123:ABC", ChatIDs: []string{"-1001", "42"}, ParseMode: parseMode},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.(*TelegramNotifier)
+}
+
+func TestTelegramPayload(t *testing.T) {
+	server := newCaptureServer(t, `{"ok":true,"result":{}}`)
+	n := newTestTelegram(t, server.URL, "")
+
+	data := template.Data{
+		Status:      "firing",
+		ExternalURL: "http://alertmanager:9093",
+		Alerts: template.Alerts{{
+			Status:       "firing",
+			Labels:       template.KV{"alertname": "HighCPU", "instance": "<node-1>", "severity": "critical"},
+			Annotations:  template.KV{"summary": "CPU > 90% & rising"},
+			GeneratorURL: "http://prometheus:9090/graph?g0.expr=up",
+		}},
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, data, nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want one per chat_id", len(requests))
+	}
+	for i, chatID := range []string{"-1001", "42"} {
+		req := requests[i]
+		if req.Path != "/bot123:ABC/sendMessage" {
+			t.Errorf("path = %s", req.Path)
+		}
+		var msg TelegramMessage
+		if err := json.Unmarshal(req.Body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.ChatID != chatID || msg.ParseMode != "HTML" || !msg.DisableWebPagePreview {
+			t.Errorf("message = %+v", msg)
+		}
+		// 标签和注解中的 HTML 特殊字符经过转义
+		for _, want := range []string{"&lt;node-1&gt;", "CPU &gt; 90% &amp; rising", `<a href="http://prometheus:9090/graph?g0.expr=up">查看图表</a>`, "静默"} {
+			if !strings.Contains(msg.Text, want) {
+				t.Errorf("text missing %q:\n%s", want, msg.Text)
+			}
+		}
+	}
+}
+
+func TestTelegramMarkdownV2Escaping(t *testing.T) {
+	n := newTestTelegram(t, "http://127.0.0.1", "MarkdownV2")
+	messages, err := n.Format(template.Data{
+		Status: "firing",
+		Alerts: template.Alerts{{Status: "firing", Labels: template.KV{"alertname": "Disk_Full.v2"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := messages[0].Payload.(TelegramMessage)
+	if msg.ParseMode != "MarkdownV2" || !strings.Contains(msg.Text, `Disk\_Full\.v2`) {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestTelegramVendorError(t *testing.T) {
+	server := newCaptureServer(t, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+	n := newTestTelegram(t, server.URL, "")
+
+	err := n.Send(context.Background(), TelegramMessage{ChatID: "42", Text: "test"})
+	var vendorErr *VendorError
+	if err == nil || !errors.As(err, &vendorErr) || vendorErr.Code != 400 {
+		t.Fatalf("err = %v, want vendor error 400", err)
+	}
+	if IsRetryable(err) {
+		t.Error("chat not found should not be retryable")
+	}
+	if strings.Contains(err.Error(), "123:ABC") {
+		t.Errorf("error leaks bot token: %v", err)
+	}
+}
+
+func TestTelegramBotTokenFromFile(t *testing.T) {
+	server := newCaptureServer(t, `{"ok":true,"result":{}}`)
+	tokenFile := filepath.Join(t.TempDir(), "bot_token")
+	if err := os.WriteFile(tokenFile, []byte("456:DEF\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := NewTelegramNotifier("telegram", Config{
+		APIURL:         server.URL,
+		TelegramConfig: TelegramConfig{BotToken: "file:" + tokenFile, ChatIDs: []string{"42"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := Dispatch(context.Background(), n, testAlerts("HighCPU"), nil); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if requests := server.Requests(); len(requests) != 1 || requests[0].Path != "/bot456:DEF/sendMessage" {
+		t.Fatalf("requests = %+v", requests)
+	}
+
+	_, err = NewTelegramNotifier("telegram", Config{TelegramConfig: TelegramConfig{BotToken: "env:TELEGRAM_BOT_TOKEN_MISSING", ChatIDs: []string{"42"}}})
+	if err == nil {
+		t.Error("expected error for unset bot token variable")
+	}
+}
@@ -0,0 +1,146 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// WeChatMessage 企业微信消息结构，markdown 类型使用 Markdown，text 类型使用 Text
+type WeChatMessage struct {
+	MsgType  string           `json:"msgtype"`
+	Markdown *MarkdownMessage `json:"markdown,omitempty"`
+	Text     *WeChatText      `json:"text,omitempty"`
+}
+
+type MarkdownMessage struct {
+	Content string `json:"content"`
+}
+
+// WeChatText 企业微信文本消息，markdown 消息不支持 @ 提醒，需要通过文本消息提醒
+type WeChatText struct {
+	Content string `json:"content"`
+	// userid 列表，@all 表示所有人
+	MentionedList       []string `json:"mentioned_list,omitempty"`
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
+}
+
+// WeChatNotifier 企业微信群机器人通知器
+type WeChatNotifier struct {
+	name       string
+	webhookURL string
+	mentioner  *mentioner
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+}
+
+// weChatRateLimitCodes 企业微信限流错误码：45009 接口调用超过限制，45033 并发调用超过限制
+var weChatRateLimitCodes = map[int]bool{45009: true, 45033: true}
+
+// weChatResponse 企业微信机器人响应体
+type weChatResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func init() {
+	Register("wechat", NewWeChatNotifier)
+}
+
+// NewWeChatNotifier 创建企业微信通知器
+func NewWeChatNotifier(name string, cfg Config) (Notifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("接收者 %s 的Webhook URL未配置", name)
+	}
+	mentioner, err := newMentioner(name, cfg.Mentions)
+	if err != nil {
+		return nil, err
+	}
+	n := &WeChatNotifier{
+		name:       name,
+		webhookURL: cfg.WebhookURL,
+		mentioner:  mentioner,
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkResponse)
+	return n, nil
+}
+
+func (w *WeChatNotifier) Name() string {
+	return w.name
+}
+
+// MaxMessageSize 企业微信 markdown 消息限制4096字节
+func (w *WeChatNotifier) MaxMessageSize() int {
+	return utils.WeChatMaxLength
+}
+
+// Format 企业微信需要按消息长度限制分批，某批告警需要 @ 提醒时紧跟该批 markdown 消息追加一条文本消息
+func (w *WeChatNotifier) Format(data template.Data) ([]Message, error) {
+	format := messageFormatter(w.template, utils.AlertFormatWechat, w.timeFormat)
+	batches := utils.SplitAlerts(data, w.MaxMessageSize(), format.Text)
+
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: WeChatMessage{
+			MsgType: "markdown",
+			Markdown: &MarkdownMessage{
+				Content: format.Text(batch),
+			},
+		}})
+
+		mentions := w.mentioner.Mentions(batch)
+		if mentions.Empty() {
+			continue
+		}
+		userIDs := mentions.UserIDs
+		if mentions.All {
+			userIDs = append([]string{"@all"}, userIDs...)
+		}
+		// 提醒消息只包含本批告警，发送失败时只重新发送本批告警，保证相关人员收到提醒
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: WeChatMessage{
+			MsgType: "text",
+			Text: &WeChatText{
+				Content:             "请相关人员尽快处理以上告警",
+				MentionedList:       userIDs,
+				MentionedMobileList: mentions.Mobiles,
+			},
+		}})
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (w *WeChatNotifier) Send(ctx context.Context, message interface{}) error {
+	return w.sender.SendAlert(ctx, w.webhookURL, message)
+}
+
+func (w *WeChatNotifier) TestConnection() error {
+	return w.sender.SendTestMessage(w.webhookURL, WeChatMessage{
+		MsgType: "markdown",
+		Markdown: &MarkdownMessage{
+			Content: "[测试连接]企业微信",
+		},
+	})
+}
+
+// checkResponse 解析企业微信响应体中的 errcode
+func (w *WeChatNotifier) checkResponse(body []byte) error {
+	var resp weChatResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Printf("[%s] 解析响应体失败，忽略错误码检查: %v", w.name, err)
+		return nil
+	}
+	if resp.ErrCode != 0 {
+		return newVendorError(w.name, resp.ErrCode, resp.ErrMsg, weChatRateLimitCodes[resp.ErrCode])
+	}
+	return nil
+}
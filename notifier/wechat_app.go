@@ -0,0 +1,296 @@
+package notifier
+
+import (
+	"alert-webhook/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// weChatAppMaxLength 企业微信应用 markdown 消息限制2048字节，留一些安全边界
+const weChatAppMaxLength = 2000
+
+// weChatAPIURL 企业微信 API 官方地址
+const weChatAPIURL = "https://qyapi.weixin.qq.com"
+
+// weChatTokenRefreshAhead access_token 提前刷新的时间，避免发送时恰好过期
+const weChatTokenRefreshAhead = 5 * time.Minute
+
+// weChatTokenExpiredCodes access_token 无效或过期的错误码：40001 不合法的 secret 或 token，40014 不合法的 token，42001 token 已过期
+var weChatTokenExpiredCodes = map[int]bool{40001: true, 40014: true, 42001: true}
+
+// WeChatAppConfig 企业微信应用消息接收者配置
+type WeChatAppConfig struct {
+	CorpID string `yaml:"corp_id"`
+	// 应用 Secret，支持 env: 和 file: 前缀
+	CorpSecret string `yaml:"corp_secret"`
+	AgentID    int64  `yaml:"agent_id"`
+	// 接收消息的成员、部门和标签 ID，至少配置一项，成员配置 @all 时发送给应用可见范围内的全部成员
+	ToUser  []string `yaml:"to_user"`
+	ToParty []string `yaml:"to_party"`
+	ToTag   []string `yaml:"to_tag"`
+}
+
+// WeChatAppMessage 企业微信应用消息请求体，多个接收者以 | 分隔
+type WeChatAppMessage struct {
+	ToUser   string           `json:"touser,omitempty"`
+	ToParty  string           `json:"toparty,omitempty"`
+	ToTag    string           `json:"totag,omitempty"`
+	MsgType  string           `json:"msgtype"`
+	AgentID  int64            `json:"agentid"`
+	Markdown *MarkdownMessage `json:"markdown"`
+}
+
+// WeChatAppNotifier 企业微信应用消息通知器，通过应用 API 发送给成员、部门或标签，不受群机器人每分钟20条的限制
+type WeChatAppNotifier struct {
+	name       string
+	apiURL     string
+	corpID     string
+	corpSecret string
+	agentID    int64
+	toUser     string
+	toParty    string
+	toTag      string
+	sender     *Sender
+	template   *utils.MessageTemplate
+	timeFormat utils.TimeFormat
+
+	tokenMu     sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// weChatAppResponse 企业微信 API 响应体，部分接收者无效时 errcode 仍为0
+type weChatAppResponse struct {
+	ErrCode      int    `json:"errcode"`
+	ErrMsg       string `json:"errmsg"`
+	InvalidUser  string `json:"invaliduser"`
+	InvalidParty string `json:"invalidparty"`
+	InvalidTag   string `json:"invalidtag"`
+}
+
+// weChatTokenResponse gettoken 接口响应体
+type weChatTokenResponse struct {
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func init() {
+	Register("wechat_app", NewWeChatAppNotifier)
+}
+
+// NewWeChatAppNotifier 创建企业微信应用消息通知器
+func NewWeChatAppNotifier(name string, cfg Config) (Notifier, error) {
+	app := cfg.WeChatAppConfig
+	if app.CorpID == "" || app.CorpSecret == "" || app.AgentID == 0 {
+		return nil, fmt.Errorf("接收者 %s 的 corp_id、corp_secret 和 agent_id 必须配置", name)
+	}
+	if len(app.ToUser) == 0 && len(app.ToParty) == 0 && len(app.ToTag) == 0 {
+		return nil, fmt.Errorf("接收者 %s 的 to_user、to_party、to_tag 至少配置一项", name)
+	}
+
+	corpSecret, err := resolveSecret(app.CorpSecret)
+	if err != nil {
+		return nil, fmt.Errorf("接收者 %s 的 corp_secret 读取失败: %w", name, err)
+	}
+
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = weChatAPIURL
+	}
+
+	n := &WeChatAppNotifier{
+		name:       name,
+		apiURL:     strings.TrimRight(apiURL, "/"),
+		corpID:     app.CorpID,
+		corpSecret: corpSecret,
+		agentID:    app.AgentID,
+		toUser:     strings.Join(app.ToUser, "|"),
+		toParty:    strings.Join(app.ToParty, "|"),
+		toTag:      strings.Join(app.ToTag, "|"),
+		template:   cfg.MessageTemplate,
+		timeFormat: cfg.timeFormat(),
+	}
+	n.sender = NewSender(name, cfg.Delivery, n.checkResponse)
+	return n, nil
+}
+
+func (w *WeChatAppNotifier) Name() string {
+	return w.name
+}
+
+func (w *WeChatAppNotifier) MaxMessageSize() int {
+	return weChatAppMaxLength
+}
+
+// Format 使用与群机器人相同的 markdown 格式，按应用消息的长度限制分批
+func (w *WeChatAppNotifier) Format(data template.Data) ([]Message, error) {
+	format := messageFormatter(w.template, utils.AlertFormatWechat, w.timeFormat)
+	batches := utils.SplitAlerts(data, w.MaxMessageSize(), format.Text)
+
+	messages := make([]Message, 0, len(batches))
+	for _, batch := range batches {
+		messages = append(messages, Message{Alerts: batch.Alerts, Payload: w.message(&MarkdownMessage{Content: format.Text(batch)})})
+	}
+	if err := format.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Send 每次请求（含重试）使用缓存的 access_token，token 失效时立即重新获取并重发一次，不受 max_retries 影响
+func (w *WeChatAppNotifier) Send(ctx context.Context, message interface{}) error {
+	return w.sender.Retry(ctx, func(ctx context.Context) error {
+		err := w.post(ctx, message)
+		if !isWeChatTokenExpired(err) {
+			return err
+		}
+		log.Printf("[%s] access_token 已失效，重新获取后重发: %v", w.name, err)
+		countAttempt(ctx)
+		return w.post(ctx, message)
+	})
+}
+
+// TestConnection 获取 access_token 校验 corp_id 和 corp_secret，不发送消息
+func (w *WeChatAppNotifier) TestConnection() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := w.accessToken(ctx)
+	return err
+}
+
+func (w *WeChatAppNotifier) message(markdown *MarkdownMessage) WeChatAppMessage {
+	return WeChatAppMessage{
+		ToUser:   w.toUser,
+		ToParty:  w.toParty,
+		ToTag:    w.toTag,
+		MsgType:  "markdown",
+		AgentID:  w.agentID,
+		Markdown: markdown,
+	}
+}
+
+// post 使用缓存的 access_token 发送一次消息
+func (w *WeChatAppNotifier) post(ctx context.Context, message interface{}) error {
+	token, err := w.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	return w.sender.post(ctx, w.sendURL(token), message)
+}
+
+func (w *WeChatAppNotifier) sendURL(token string) string {
+	return w.apiURL + "/cgi-bin/message/send?access_token=" + url.QueryEscape(token)
+}
+
+// accessToken 返回缓存的 access_token，未获取或即将过期时重新获取
+func (w *WeChatAppNotifier) accessToken(ctx context.Context) (string, error) {
+	w.tokenMu.Lock()
+	defer w.tokenMu.Unlock()
+
+	if w.token != "" && time.Now().Before(w.tokenExpiry) {
+		return w.token, nil
+	}
+
+	token, expiresIn, err := w.fetchToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	lifetime := time.Duration(expiresIn) * time.Second
+	w.token = token
+	w.tokenExpiry = time.Now().Add(lifetime - min(weChatTokenRefreshAhead, lifetime/2))
+	log.Printf("[%s] 获取 access_token 成功，有效期 %d 秒", w.name, expiresIn)
+	return token, nil
+}
+
+// invalidateToken 清除缓存的 access_token
+func (w *WeChatAppNotifier) invalidateToken() {
+	w.tokenMu.Lock()
+	defer w.tokenMu.Unlock()
+	w.token = ""
+}
+
+// fetchToken 调用 gettoken 接口，请求地址中包含 corpsecret，错误信息中不包含请求地址
+func (w *WeChatAppNotifier) fetchToken(ctx context.Context) (string, int, error) {
+	tokenURL := w.apiURL + "/cgi-bin/gettoken?corpid=" + url.QueryEscape(w.corpID) + "&corpsecret=" + url.QueryEscape(w.corpSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return "", 0, &SendError{Err: fmt.Errorf("[%s] 创建获取 access_token 请求失败: %w", w.name, stripURL(err))}
+	}
+
+	resp, err := w.sender.httpClient.Do(req)
+	if err != nil {
+		return "", 0, &SendError{Retryable: true, Err: fmt.Errorf("[%s] 获取 access_token 失败: %w", w.name, stripURL(err))}
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("[%s] 关闭响应体失败: %v", w.name, err)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, &SendError{Retryable: true, Err: fmt.Errorf("[%s] 读取 access_token 响应失败: %w", w.name, err)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, &SendError{
+			StatusCode: resp.StatusCode,
+			Retryable:  resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+			Err:        fmt.Errorf("[%s] 获取 access_token 返回错误状态码: %d, 响应: %s", w.name, resp.StatusCode, string(body)),
+		}
+	}
+
+	var tokenResp weChatTokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", 0, &SendError{Retryable: true, Err: fmt.Errorf("[%s] 解析 access_token 响应失败: %w", w.name, err)}
+	}
+	if tokenResp.ErrCode != 0 || tokenResp.AccessToken == "" {
+		// corp_id 或 corp_secret 错误时重试无意义，限流错误码等待后重试
+		return "", 0, newVendorError(w.name, tokenResp.ErrCode, tokenResp.ErrMsg, weChatRateLimitCodes[tokenResp.ErrCode])
+	}
+	return tokenResp.AccessToken, tokenResp.ExpiresIn, nil
+}
+
+// checkResponse 解析应用消息响应体，token 失效时清除缓存并返回可重试错误，部分接收者无效时只记录日志
+func (w *WeChatAppNotifier) checkResponse(body []byte) error {
+	var resp weChatAppResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Printf("[%s] 解析响应体失败，忽略错误码检查: %v", w.name, err)
+		return nil
+	}
+
+	if weChatTokenExpiredCodes[resp.ErrCode] {
+		w.invalidateToken()
+		return &SendError{
+			StatusCode: http.StatusOK,
+			Retryable:  true,
+			Err:        fmt.Errorf("[%s] access_token 已失效，重新获取后重试: %w", w.name, &VendorError{Code: resp.ErrCode, Message: resp.ErrMsg}),
+		}
+	}
+	if resp.ErrCode != 0 {
+		return newVendorError(w.name, resp.ErrCode, resp.ErrMsg, weChatRateLimitCodes[resp.ErrCode])
+	}
+
+	if resp.InvalidUser != "" || resp.InvalidParty != "" || resp.InvalidTag != "" {
+		log.Printf("[%s] 部分接收者无效，成员: [%s] 部门: [%s] 标签: [%s]", w.name, resp.InvalidUser, resp.InvalidParty, resp.InvalidTag)
+	}
+	return nil
+}
+
+// isWeChatTokenExpired 判断发送错误是否为 access_token 无效或过期
+func isWeChatTokenExpired(err error) bool {
+	var vendorErr *VendorError
+	return errors.As(err, &vendorErr) && weChatTokenExpiredCodes[vendorErr.Code]
+}
@@ -0,0 +1,146 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeWeChatAPI 企业微信 API 模拟服务，每次 gettoken 签发新的 token，expired 中的 token 发送时返回 42001
+type fakeWeChatAPI struct {
+	mu       sync.Mutex
+	tokens   int
+	expired  map[string]bool
+	sent     []string
+	messages []WeChatAppMessage
+}
+
+func (f *fakeWeChatAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/cgi-bin/gettoken":
+		if r.URL.Query().Get("corpid") != "corp" || r.URL.Query().Get("corpsecret") != "secret" {
+			fmt.Fprint(w, `{"errcode":40013,"errmsg":"invalid corpid"}`)
+			return
+		}
+		f.tokens++
+		fmt.Fprintf(w, `{"errcode":0,"errmsg":"ok","access_token":"token-%d","expires_in":7200}`, f.tokens)
+	case "/cgi-bin/message/send":
+		token := r.URL.Query().Get("access_token")
+		f.sent = append(f.sent, token)
+		if f.expired[token] {
+			fmt.Fprint(w, `{"errcode":42001,"errmsg":"access_token expired"}`)
+			return
+		}
+		var msg WeChatAppMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+		f.messages = append(f.messages, msg)
+		fmt.Fprint(w, `{"errcode":0,"errmsg":"ok","invaliduser":""}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestWeChatApp(t *testing.T, api *fakeWeChatAPI) *WeChatAppNotifier {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	maxRetries := 0
+	n, err := NewWeChatAppNotifier("wechat-app", Config{
+		APIURL:   server.URL,
+		Delivery: DeliveryConfig{MaxRetries: &maxRetries},
+		WeChatAppConfig: WeChatAppConfig{
+			CorpID:     "corp",
+			CorpSecret: "secret",
+			AgentID:    1000002,
+			ToUser:     []string{"zhangsan", "lisi"},
+			ToParty:    []string{"2"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.(*WeChatAppNotifier)
+}
+
+func TestWeChatAppCachesAccessToken(t *testing.T) {
+	api := &fakeWeChatAPI{}
+	n := newTestWeChatApp(t, api)
+
+	messages, err := n.Format(testAlerts("a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := n.Send(context.Background(), messages[0].Payload); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+
+	if api.tokens != 1 {
+		t.Errorf("gettoken called %d times, want 1", api.tokens)
+	}
+	msg := api.messages[0]
+	if msg.ToUser != "zhangsan|lisi" || msg.ToParty != "2" || msg.AgentID != 1000002 || msg.MsgType != "markdown" {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestWeChatAppRefreshesExpiredTokenWithoutRetries(t *testing.T) {
+	api := &fakeWeChatAPI{expired: map[string]bool{"token-1": true}}
+	n := newTestWeChatApp(t, api)
+	ctx, attempts := WithAttemptCounter(context.Background())
+
+	// max_retries 为0时 token 过期仍立即重新获取并重发一次
+	if err := n.Send(ctx, n.message(&MarkdownMessage{Content: "test"})); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if api.tokens != 2 {
+		t.Errorf("gettoken called %d times, want 2", api.tokens)
+	}
+	if len(api.sent) != 2 || api.sent[0] != "token-1" || api.sent[1] != "token-2" {
+		t.Errorf("send requests used tokens %v, want [token-1 token-2]", api.sent)
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("attempts = %d, want 2", attempts.Load())
+	}
+
+	// 之后使用刷新后的 token
+	if err := n.Send(context.Background(), n.message(&MarkdownMessage{Content: "test"})); err != nil {
+		t.Fatal(err)
+	}
+	if api.tokens != 2 || api.sent[2] != "token-2" {
+		t.Errorf("gettoken/sent = %d/%v, want cached token-2", api.tokens, api.sent)
+	}
+}
+
+func TestWeChatAppTokenStillExpiredAfterRefresh(t *testing.T) {
+	api := &fakeWeChatAPI{expired: map[string]bool{"token-1": true, "token-2": true}}
+	n := newTestWeChatApp(t, api)
+
+	err := n.Send(context.Background(), n.message(&MarkdownMessage{Content: "test"}))
+	if err == nil || !isWeChatTokenExpired(err) {
+		t.Fatalf("err = %v, want token expired error", err)
+	}
+	if len(api.sent) != 2 {
+		t.Errorf("send requests = %d, want 2", len(api.sent))
+	}
+}
+
+func TestWeChatAppInvalidCredentials(t *testing.T) {
+	api := &fakeWeChatAPI{}
+	n := newTestWeChatApp(t, api)
+	n.corpSecret = "wrong"
+
+	err := n.TestConnection()
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("TestConnection = %v, want non-retryable error", err)
+	}
+}
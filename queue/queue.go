@@ -0,0 +1,455 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+const (
+	walFileName        = "queue.wal"
+	deadLetterFileName = "dead_letters.jsonl"
+
+	// compactThreshold WAL 记录数超过该值且大部分已确认时触发压缩
+	compactThreshold = 1000
+)
+
+// Item 待投递的告警，一个 Item 对应一个接收者的一组告警
+type Item struct {
+	ID       string        `json:"id"`
+	Receiver string        `json:"receiver"`
+	Data     template.Data `json:"data"`
+	// 已成功发送的告警指纹，重新投递时跳过
+	Delivered []string `json:"delivered_alerts,omitempty"`
+	// 已投递次数
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error,omitempty"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// DeadLetter 永久失败的投递记录
+type DeadLetter struct {
+	Item     Item      `json:"item"`
+	Reason   string    `json:"reason"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// record WAL 中的一条记录
+type record struct {
+	Op   string `json:"op"`
+	Item *Item  `json:"item,omitempty"`
+	ID   string `json:"id,omitempty"`
+	// 以下字段仅用于 progress 记录
+	Delivered []string `json:"delivered_alerts,omitempty"`
+	Attempts  int      `json:"attempts,omitempty"`
+	LastError string   `json:"last_error,omitempty"`
+}
+
+const (
+	opEnqueue  = "enqueue"
+	opProgress = "progress"
+	opAck      = "ack"
+)
+
+// Queue 基于追加写 WAL 的持久化投递队列，按接收者维护先进先出顺序
+type Queue struct {
+	mu       sync.Mutex
+	dir      string
+	wal      *os.File
+	records  int
+	items    map[string]*Item
+	fifo     map[string][]string
+	notify   map[string]chan struct{}
+	sequence atomic.Uint64
+}
+
+// Open 打开 dataDir 下的队列，重放 WAL 恢复未完成的投递
+func Open(dataDir string) (*Queue, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建队列数据目录失败: %w", err)
+	}
+
+	q := &Queue{
+		dir:    dataDir,
+		items:  make(map[string]*Item),
+		fifo:   make(map[string][]string),
+		notify: make(map[string]chan struct{}),
+	}
+
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+
+	// 启动时压缩一次，只保留未完成的投递
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// replay 读取 WAL 重建内存状态
+func (q *Queue) replay() error {
+	file, err := os.Open(q.walPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("打开队列 WAL 失败: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// 进程异常退出时最后一行可能不完整，跳过即可
+			log.Printf("跳过无法解析的队列记录: %v", err)
+			continue
+		}
+
+		switch rec.Op {
+		case opEnqueue:
+			if rec.Item != nil {
+				q.add(rec.Item)
+			}
+		case opProgress:
+			if item, ok := q.items[rec.ID]; ok {
+				item.Delivered = rec.Delivered
+				item.Attempts = rec.Attempts
+				item.LastError = rec.LastError
+			}
+		case opAck:
+			q.remove(rec.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取队列 WAL 失败: %w", err)
+	}
+
+	if len(q.items) > 0 {
+		log.Printf("从队列 WAL 恢复 %d 条未完成的投递", len(q.items))
+	}
+	return nil
+}
+
+// compact 将未完成的投递重写为新的 WAL
+func (q *Queue) compact() error {
+	tmpPath := q.walPath() + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("创建队列 WAL 临时文件失败: %w", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	records := 0
+	for _, item := range q.ordered() {
+		line, err := json.Marshal(record{Op: opEnqueue, Item: item})
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("序列化队列记录失败: %w", err)
+		}
+		writer.Write(append(line, '\n'))
+		records++
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入队列 WAL 临时文件失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("同步队列 WAL 临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("关闭队列 WAL 临时文件失败: %w", err)
+	}
+
+	if q.wal != nil {
+		q.wal.Close()
+	}
+	if err := os.Rename(tmpPath, q.walPath()); err != nil {
+		return fmt.Errorf("替换队列 WAL 失败: %w", err)
+	}
+
+	q.wal, err = os.OpenFile(q.walPath(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开队列 WAL 失败: %w", err)
+	}
+	q.records = records
+	return nil
+}
+
+// Enqueue 将告警写入 WAL 并落盘，返回后即使进程退出也会在下次启动时重新投递
+func (q *Queue) Enqueue(receiver string, data template.Data) (*Item, error) {
+	item := &Item{
+		ID:         fmt.Sprintf("%d-%d", time.Now().UnixNano(), q.sequence.Add(1)),
+		Receiver:   receiver,
+		Data:       data,
+		EnqueuedAt: time.Now(),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := q.append(record{Op: opEnqueue, Item: item}, true); err != nil {
+		return nil, err
+	}
+	q.add(item)
+	q.signal(receiver)
+	return item, nil
+}
+
+// Peek 返回接收者队首的投递，不从队列中移除
+func (q *Queue) Peek(receiver string) (*Item, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ids := q.fifo[receiver]
+	if len(ids) == 0 {
+		return nil, false
+	}
+	item := *q.items[ids[0]]
+	return &item, true
+}
+
+// Wait 返回接收者有新投递时会收到通知的 channel
+func (q *Queue) Wait(receiver string) <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.channel(receiver)
+}
+
+// Progress 记录一次投递尝试的结果，投递仍保留在队列中
+func (q *Queue) Progress(item *Item) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	current, ok := q.items[item.ID]
+	if !ok {
+		return nil
+	}
+	current.Delivered = item.Delivered
+	current.Attempts = item.Attempts
+	current.LastError = item.LastError
+
+	return q.append(record{
+		Op:        opProgress,
+		ID:        item.ID,
+		Delivered: item.Delivered,
+		Attempts:  item.Attempts,
+		LastError: item.LastError,
+	}, false)
+}
+
+// Ack 投递完成，从队列中移除
+func (q *Queue) Ack(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.items[id]; !ok {
+		return nil
+	}
+	q.remove(id)
+	if err := q.append(record{Op: opAck, ID: id}, false); err != nil {
+		return err
+	}
+
+	if q.records > compactThreshold && q.records > 4*len(q.items) {
+		return q.compact()
+	}
+	return nil
+}
+
+// DeadLetter 将永久失败的投递写入死信文件，并从队列中移除
+func (q *Queue) DeadLetter(item *Item, reason string) error {
+	if err := q.AppendDeadLetter(item, reason); err != nil {
+		return err
+	}
+	return q.Ack(item.ID)
+}
+
+// AppendDeadLetter 只写入死信文件，不从队列中移除，用于投递中部分告警永久失败、其余告警继续重试
+func (q *Queue) AppendDeadLetter(item *Item, reason string) error {
+	line, err := json.Marshal(DeadLetter{Item: *item, Reason: reason, FailedAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("序列化死信记录失败: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// 与 WAL 一样立即落盘，进程崩溃后死信记录不会丢失
+	file, err := os.OpenFile(q.deadLetterPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = file.Write(append(line, '\n'))
+		if err == nil {
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("写入死信文件失败: %w", err)
+	}
+	return nil
+}
+
+// DeadLetters 读取全部死信记录
+func (q *Queue) DeadLetters() ([]DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := os.Open(q.deadLetterPath())
+	if os.IsNotExist(err) {
+		return []DeadLetter{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开死信文件失败: %w", err)
+	}
+	defer file.Close()
+
+	letters := make([]DeadLetter, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	return letters, scanner.Err()
+}
+
+// Receivers 返回当前有未完成投递的接收者
+func (q *Queue) Receivers() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	receivers := make([]string, 0, len(q.fifo))
+	for receiver, ids := range q.fifo {
+		if len(ids) > 0 {
+			receivers = append(receivers, receiver)
+		}
+	}
+	sort.Strings(receivers)
+	return receivers
+}
+
+// Len 返回未完成投递的数量
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Close 关闭 WAL 文件
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.wal == nil {
+		return nil
+	}
+	err := q.wal.Close()
+	q.wal = nil
+	return err
+}
+
+// append 追加一条 WAL 记录，sync 为 true 时立即落盘
+func (q *Queue) append(rec record, sync bool) error {
+	if q.wal == nil {
+		return fmt.Errorf("队列已关闭")
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("序列化队列记录失败: %w", err)
+	}
+	if _, err := q.wal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入队列 WAL 失败: %w", err)
+	}
+	if sync {
+		if err := q.wal.Sync(); err != nil {
+			return fmt.Errorf("同步队列 WAL 失败: %w", err)
+		}
+	}
+	q.records++
+	return nil
+}
+
+func (q *Queue) add(item *Item) {
+	q.items[item.ID] = item
+	q.fifo[item.Receiver] = append(q.fifo[item.Receiver], item.ID)
+}
+
+func (q *Queue) remove(id string) {
+	item, ok := q.items[id]
+	if !ok {
+		return
+	}
+	delete(q.items, id)
+
+	ids := q.fifo[item.Receiver]
+	for i, queued := range ids {
+		if queued == id {
+			q.fifo[item.Receiver] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// ordered 按入队顺序返回全部未完成的投递，入队时间相同时保持每个接收者队列中的先后顺序
+func (q *Queue) ordered() []*Item {
+	receivers := make([]string, 0, len(q.fifo))
+	for receiver := range q.fifo {
+		receivers = append(receivers, receiver)
+	}
+	sort.Strings(receivers)
+
+	items := make([]*Item, 0, len(q.items))
+	for _, receiver := range receivers {
+		for _, id := range q.fifo[receiver] {
+			items = append(items, q.items[id])
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].EnqueuedAt.Before(items[j].EnqueuedAt)
+	})
+	return items
+}
+
+func (q *Queue) channel(receiver string) chan struct{} {
+	ch, ok := q.notify[receiver]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		q.notify[receiver] = ch
+	}
+	return ch
+}
+
+func (q *Queue) signal(receiver string) {
+	select {
+	case q.channel(receiver) <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) walPath() string {
+	return filepath.Join(q.dir, walFileName)
+}
+
+func (q *Queue) deadLetterPath() string {
+	return filepath.Join(q.dir, deadLetterFileName)
+}
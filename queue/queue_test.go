@@ -0,0 +1,243 @@
+package queue
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func testData(names ...string) template.Data {
+	data := template.Data{Status: "firing"}
+	for _, name := range names {
+		data.Alerts = append(data.Alerts, template.Alert{
+			Status: "firing",
+			Labels: template.KV{"alertname": name},
+		})
+	}
+	return data
+}
+
+func openQueue(t *testing.T, dir string) *Queue {
+	t.Helper()
+	q, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func enqueue(t *testing.T, q *Queue, receiver string, names ...string) *Item {
+	t.Helper()
+	item, err := q.Enqueue(receiver, testData(names...))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	return item
+}
+
+// walLines 返回 WAL 文件的记录数
+func walLines(t *testing.T, dir string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, walFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestQueueReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir)
+
+	first := enqueue(t, q, "wechat", "a")
+	second := enqueue(t, q, "wechat", "b")
+	acked := enqueue(t, q, "wechat", "c")
+	other := enqueue(t, q, "slack", "d")
+
+	if err := q.Ack(acked.ID); err != nil {
+		t.Fatal(err)
+	}
+	first.Attempts = 2
+	first.Delivered = []string{"fp-a"}
+	first.LastError = "503"
+	if err := q.Progress(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	q = openQueue(t, dir)
+	if q.Len() != 3 {
+		t.Fatalf("Len after restart = %d, want 3", q.Len())
+	}
+	if got := q.Receivers(); !reflect.DeepEqual(got, []string{"slack", "wechat"}) {
+		t.Errorf("Receivers = %v, want [slack wechat]", got)
+	}
+
+	head, ok := q.Peek("wechat")
+	if !ok || head.ID != first.ID {
+		t.Fatalf("Peek(wechat) = %v, want %s", head, first.ID)
+	}
+	if head.Attempts != 2 || head.LastError != "503" || !reflect.DeepEqual(head.Delivered, []string{"fp-a"}) {
+		t.Errorf("progress not replayed: attempts=%d last_error=%q delivered=%v", head.Attempts, head.LastError, head.Delivered)
+	}
+	if head.Data.Alerts[0].Labels["alertname"] != "a" {
+		t.Errorf("replayed data = %v", head.Data)
+	}
+
+	// 确认队首后按入队顺序继续
+	if err := q.Ack(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := q.Peek("wechat"); head.ID != second.ID {
+		t.Errorf("Peek after ack = %s, want %s", head.ID, second.ID)
+	}
+	if head, _ := q.Peek("slack"); head.ID != other.ID {
+		t.Errorf("Peek(slack) = %s, want %s", head.ID, other.ID)
+	}
+}
+
+func TestQueueReplaySkipsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir)
+	item := enqueue(t, q, "wechat", "a")
+	q.Close()
+
+	// 模拟进程在写入最后一条记录时退出
+	wal, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.WriteString(`{"op":"ack","id":"` + item.ID[:3])
+	wal.Close()
+
+	q = openQueue(t, dir)
+	if head, ok := q.Peek("wechat"); !ok || head.ID != item.ID {
+		t.Fatalf("Peek = %v, %v; want %s", head, ok, item.ID)
+	}
+}
+
+func TestQueueCompaction(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir)
+
+	pending := enqueue(t, q, "wechat", "pending")
+	for i := 0; i < compactThreshold; i++ {
+		item := enqueue(t, q, "wechat", "done")
+		if err := q.Ack(item.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 记录数超过阈值后压缩，只保留未确认的投递
+	if lines := walLines(t, dir); lines >= compactThreshold {
+		t.Errorf("WAL has %d records after compaction threshold, want fewer than %d", lines, compactThreshold)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", q.Len())
+	}
+
+	// 压缩后继续追加的记录在重启后仍能重放
+	later := enqueue(t, q, "wechat", "later")
+	q.Close()
+
+	q = openQueue(t, dir)
+	if lines := walLines(t, dir); lines != 2 {
+		t.Errorf("WAL has %d records after reopen, want 2", lines)
+	}
+	head, ok := q.Peek("wechat")
+	if !ok || head.ID != pending.ID {
+		t.Fatalf("Peek = %v, want %s", head, pending.ID)
+	}
+	if err := q.Ack(pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := q.Peek("wechat"); head.ID != later.ID {
+		t.Errorf("Peek after ack = %s, want %s", head.ID, later.ID)
+	}
+}
+
+func TestQueueDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir)
+
+	if letters, err := q.DeadLetters(); err != nil || len(letters) != 0 {
+		t.Fatalf("DeadLetters on empty queue = %v, %v", letters, err)
+	}
+
+	item := enqueue(t, q, "wechat", "a")
+	kept := enqueue(t, q, "wechat", "b")
+	item.Attempts = 3
+	if err := q.DeadLetter(item, "errcode 93000"); err != nil {
+		t.Fatalf("DeadLetter: %v", err)
+	}
+
+	if head, _ := q.Peek("wechat"); head.ID != kept.ID {
+		t.Errorf("dead-lettered item still at queue head")
+	}
+	q.Close()
+
+	// 死信在重启后仍可读取，且不会重新投递
+	q = openQueue(t, dir)
+	if q.Len() != 1 {
+		t.Errorf("Len after restart = %d, want 1", q.Len())
+	}
+	letters, err := q.DeadLetters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("DeadLetters = %v, want 1 record", letters)
+	}
+	letter := letters[0]
+	if letter.Item.ID != item.ID || letter.Item.Receiver != "wechat" || letter.Item.Attempts != 3 {
+		t.Errorf("dead letter item = %+v", letter.Item)
+	}
+	if letter.Reason != "errcode 93000" || letter.FailedAt.IsZero() {
+		t.Errorf("dead letter reason/failed_at = %q/%v", letter.Reason, letter.FailedAt)
+	}
+	if letter.Item.Data.Alerts[0].Labels["alertname"] != "a" {
+		t.Errorf("dead letter data = %v", letter.Item.Data)
+	}
+}
+
+func TestQueueCompactionKeepsOrderForEqualTimestamps(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir)
+
+	// 同一时间入队的告警，压缩和重放后仍按入队顺序投递
+	enqueuedAt := time.Now()
+	var want []string
+	for i := 0; i < 20; i++ {
+		item := enqueue(t, q, "wechat", "a")
+		item.EnqueuedAt = enqueuedAt
+		want = append(want, item.ID)
+	}
+	if err := q.compact(); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	q = openQueue(t, dir)
+	var got []string
+	for {
+		item, ok := q.Peek("wechat")
+		if !ok {
+			break
+		}
+		got = append(got, item.ID)
+		if err := q.Ack(item.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed order = %v, want %v", got, want)
+	}
+}
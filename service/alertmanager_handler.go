@@ -3,10 +3,10 @@ package service
 import (
 	"alert-webhook/config"
 	"alert-webhook/utils"
-	"fmt"
+	"context"
+	"errors"
 	"log"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,26 +14,32 @@ import (
 	"github.com/prometheus/alertmanager/template"
 )
 
-// GinAlertHandler 处理告警
-func GinAlertHandler(notifiers map[string]string, enabledClients []string, appConfig *config.AppConfig) gin.HandlerFunc {
+// GinAlertHandler 处理告警，响应体为 DeliveryReport JSON，包含每个接收者的投递结果
+func GinAlertHandler(delivery *DeliveryService, appConfig *config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		report := NewDeliveryReport(0)
+
 		if c.Request.Method != http.MethodPost {
-			c.String(http.StatusMethodNotAllowed, "仅支持POST请求")
+			report.Reply(ReportRejected, "仅支持POST请求")
+			c.JSON(http.StatusMethodNotAllowed, report)
 			return
 		}
 
 		var data template.Data
 		if err := c.ShouldBindJSON(&data); err != nil {
 			log.Printf("解析Alertmanager请求失败: %v", err)
-			c.String(http.StatusBadRequest, "无效的请求体")
+			report.Reply(ReportRejected, "无效的请求体")
+			c.JSON(http.StatusBadRequest, report)
 			return
 		}
+		report.Alerts = len(data.Alerts)
 
 		// 过滤无效告警
 		validAlerts := utils.FilterValidAlerts(data.Alerts)
 		if len(validAlerts) == 0 {
 			log.Println("所有告警的 severity 都为 none，忽略发送")
-			c.String(http.StatusOK, "无有效告警，无需发送")
+			report.Reply(ReportFiltered, "无有效告警，无需发送")
+			c.JSON(http.StatusOK, report)
 			return
 		}
 
@@ -42,18 +48,20 @@ func GinAlertHandler(notifiers map[string]string, enabledClients []string, appCo
 		for _, alert := range validAlerts {
 			alertName := alert.Labels["alertname"]
 			severity := alert.Labels["severity"]
+			status := utils.AlertStatus(alert, data.Status)
 
 			if appConfig.ShouldSendAlert(alertName, severity) {
 				filteredAlerts = append(filteredAlerts, alert)
-				log.Printf("告警 [%s] 级别 [%s] 通过过滤规则", alertName, severity)
+				log.Printf("告警 [%s] 级别 [%s] 状态 [%s] 通过过滤规则", alertName, severity, status)
 			} else {
-				log.Printf("告警 [%s] 级别 [%s] 被过滤规则拦截", alertName, severity)
+				log.Printf("告警 [%s] 级别 [%s] 状态 [%s] 被过滤规则拦截", alertName, severity, status)
 			}
 		}
 
 		if len(filteredAlerts) == 0 {
 			log.Println("所有告警都被过滤规则拦截，忽略发送")
-			c.String(http.StatusOK, "所有告警都被过滤，无需发送")
+			report.Reply(ReportFiltered, "所有告警都被过滤，无需发送")
+			c.JSON(http.StatusOK, report)
 			return
 		}
 
@@ -61,115 +69,119 @@ func GinAlertHandler(notifiers map[string]string, enabledClients []string, appCo
 		data.Alerts = filteredAlerts
 		log.Printf("过滤后剩余 %d 个告警将被发送", len(filteredAlerts))
 
-		var wg sync.WaitGroup
-		failedClients := make([]string, 0)
+		// 按路由树为每个告警选择接收者，发往同一接收者的告警合并发送
+		receivers, groups, unrouted := routeAlerts(appConfig, data)
+		if len(unrouted.Alerts) > 0 {
+			delivery.AuditUnrouted(unrouted)
+		}
+		if len(receivers) == 0 {
+			log.Println("没有需要发送告警的接收者，忽略发送")
+			report.Reply(ReportFiltered, "没有需要发送告警的接收者")
+			c.JSON(http.StatusOK, report)
+			return
+		}
+		for _, client := range receivers {
+			log.Printf("[%s] 路由到 %d 个告警，状态 %s", client, len(groups[client].Alerts), groups[client].Status)
+		}
 
-		for _, client := range enabledClients {
-			webhookURL, ok := notifiers[client]
+		// 先写入持久化队列再返回，保证进程退出或平台故障时告警不丢失
+		results, err := delivery.Submit(receivers, groups)
+		if errors.Is(err, ErrQueueFull) {
+			log.Printf("投递队列已满，拒绝本次告警")
+			report.Reply(ReportRejected, "投递队列已满，请稍后重试")
+			c.JSON(http.StatusServiceUnavailable, report)
+			return
+		}
+		if err != nil {
+			log.Printf("告警写入投递队列失败: %v", err)
+			report.Reply(ReportFailed, "告警写入投递队列失败")
+			c.JSON(http.StatusInternalServerError, report)
+			return
+		}
+
+		// 异步模式下写入队列后立即返回，由投递服务在后台发送
+		if appConfig.Server.Async {
+			report.Reply(ReportAccepted, "告警已接收，正在异步投递")
+			c.JSON(http.StatusAccepted, report)
+			return
+		}
+
+		// 等待每个接收者的首次投递结果，失败的告警保留在队列中继续重试
+		// 队首告警重试或请求被取消时不再等待，未完成的投递由队列继续完成
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(appConfig.Server.SyncTimeout)*time.Second)
+		defer cancel()
+		for _, client := range receivers {
+			ch, ok := results[client]
 			if !ok {
-				log.Printf("客户端 %s 未配置", client)
 				continue
 			}
 
-			wg.Add(1)
-			go func(client, url string) {
-				defer wg.Done()
-
-				// 企业微信需要特殊处理消息长度限制
-				if client == "wechat" {
-					// 将告警分批处理
-					alertBatches := utils.SplitWeChatAlerts(data)
-					log.Printf("[%s] 告警分为 %d 批发送", client, len(alertBatches))
-
-					batchSuccess := 0
-					for i, batchData := range alertBatches {
-						// 格式化当前批次的消息
-						message := WeChatMessage{
-							MsgType: "markdown",
-							Markdown: MarkdownMessage{
-								Content: utils.AlertFormatWechat(batchData),
-							},
-						}
-
-						log.Printf("[%s] 发送第 %d/%d 批消息，包含 %d 个告警", client, i+1, len(alertBatches), len(batchData.Alerts))
-
-						if err := SendAlert(client, url, message); err != nil {
-							log.Printf("[%s] 第 %d 批消息发送失败: %v", client, i+1, err)
-						} else {
-							log.Printf("[%s] 第 %d 批消息发送成功", client, i+1)
-							batchSuccess++
-						}
-
-						// 批次之间添加小延迟，避免频率限制
-						if i < len(alertBatches)-1 {
-							time.Sleep(200 * time.Millisecond)
-						}
-					}
-
-					// 检查是否所有批次都成功
-					if batchSuccess != len(alertBatches) {
-						failedClients = append(failedClients, fmt.Sprintf("%s(%d/%d批成功)", client, batchSuccess, len(alertBatches)))
-					}
-					return
-				}
-
-				// 其他客户端使用原有逻辑
-				message, err := formatMessageForClient(client, data)
-				if err != nil {
-					log.Printf("[%s] 格式化消息失败: %v", client, err)
-					failedClients = append(failedClients, client)
-					return
-				}
-
-				// 发送消息
-				if err := SendAlert(client, url, message); err != nil {
-					log.Printf("[%s] 发送告警失败: %v", client, err)
-					failedClients = append(failedClients, client)
-				} else {
-					log.Printf("[%s] 告警发送成功", client)
-				}
-			}(client, webhookURL)
+			result, ok := waitResult(ctx, ch)
+			switch {
+			case !ok:
+				result = newPendingResult(client, len(groups[client].Alerts))
+				log.Printf("[%s] 等待首次投递结果超时，告警保留在投递队列中继续投递", client)
+			case result.Status == ReceiverSuccess:
+				log.Printf("[%s] 告警发送成功，耗时 %dms", client, result.LatencyMs)
+			default:
+				log.Printf("[%s] 发送告警失败(%d/%d批成功): %s", client, result.DeliveredBatches, result.TotalBatches, result.Error)
+			}
+			report.Add(result)
 		}
 
-		wg.Wait()
-
-		if len(failedClients) > 0 {
-			c.String(http.StatusInternalServerError,
-				fmt.Sprintf("部分客户端发送失败: %v", failedClients))
-		} else {
-			c.String(http.StatusOK, "告警已成功发送到所有客户端")
-		}
+		c.JSON(report.Finish(), report)
 	}
 }
 
-func formatMessageForClient(client string, data template.Data) (interface{}, error) {
-	switch client {
-	case "wechat":
-		log.Printf("转换企业微信格式")
-		return WeChatMessage{
-			MsgType: "markdown",
-			Markdown: MarkdownMessage{
-				Content: utils.AlertFormatWechat(data),
-			},
-		}, nil
-	case "dingtalk":
-		log.Printf("转换钉钉格式")
-		return DingTalkMessage{
-			MsgType: "markdown",
-			Markdown: DingTalkMarkdown{
-				Title: "Prometheus告警",
-				Text:  utils.AlertFormatDingtalk(data),
-			},
-		}, nil
-	case "feishu":
-		log.Printf("转换飞书格式")
-		return FeishuMessage{
-			MsgType: "text",
-			Content: FeishuContent{
-				Text: utils.AlertFormatFeishu(data),
-			},
-		}, nil
+// waitResult 等待接收者的首次投递结果，ctx 结束时返回 false；结果已就绪时优先返回结果
+func waitResult(ctx context.Context, ch <-chan ReceiverResult) (ReceiverResult, bool) {
+	select {
+	case result := <-ch:
+		return result, true
 	default:
-		return nil, fmt.Errorf("未知客户端类型: %s", client)
 	}
+
+	select {
+	case result := <-ch:
+		return result, true
+	case <-ctx.Done():
+		return ReceiverResult{}, false
+	}
+}
+
+// routeAlerts 按路由树逐个匹配告警，返回接收者名称（按首次命中顺序）、每个接收者对应的告警数据，以及没有发往任何接收者的告警
+// 未开启 send_resolved 的接收者不接收已恢复的告警，每个接收者的分组状态按其告警重新计算
+func routeAlerts(appConfig *config.AppConfig, data template.Data) ([]string, map[string]template.Data, template.Data) {
+	var receivers []string
+	groups := make(map[string]template.Data)
+	unrouted := data
+	unrouted.Alerts = nil
+
+	for _, alert := range data.Alerts {
+		resolved := utils.AlertStatus(alert, data.Status) == "resolved"
+		routed := false
+		for _, receiver := range appConfig.MatchReceivers(alert) {
+			if resolved && !appConfig.Notifiers[receiver].ShouldSendResolved() {
+				log.Printf("[%s] 未开启恢复通知，忽略已恢复告警 [%s]", receiver, alert.Labels["alertname"])
+				continue
+			}
+			routed = true
+
+			group, ok := groups[receiver]
+			if !ok {
+				receivers = append(receivers, receiver)
+				group = data
+				group.Receiver = receiver
+				group.Alerts = nil
+			}
+			group.Alerts = append(group.Alerts, alert)
+			group.Status = utils.GroupStatus(group.Alerts, data.Status)
+			groups[receiver] = group
+		}
+		if !routed {
+			unrouted.Alerts = append(unrouted.Alerts, alert)
+		}
+	}
+
+	return receivers, groups, unrouted
 }
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"alert-webhook/audit"
+	"alert-webhook/config"
+	"alert-webhook/notifier"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// stubNotifier 每个告警一条消息；err 非空时每次发送都返回 err，block 非空时发送阻塞到 block 关闭，inflight 为进行中的发送数
+type stubNotifier struct {
+	name  string
+	err   error
+	block chan struct{}
+
+	sent     atomic.Int32
+	inflight atomic.Int32
+}
+
+func (s *stubNotifier) Name() string          { return s.name }
+func (s *stubNotifier) MaxMessageSize() int   { return 0 }
+func (s *stubNotifier) TestConnection() error { return nil }
+
+func (s *stubNotifier) Format(data template.Data) ([]notifier.Message, error) {
+	messages := make([]notifier.Message, 0, len(data.Alerts))
+	for _, alert := range data.Alerts {
+		messages = append(messages, notifier.Message{Alerts: []template.Alert{alert}, Payload: alert.Labels["alertname"]})
+	}
+	return messages, nil
+}
+
+func (s *stubNotifier) Send(ctx context.Context, _ interface{}) error {
+	s.inflight.Add(1)
+	defer s.inflight.Add(-1)
+
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.sent.Add(1)
+	return nil
+}
+
+// newTestConfig 告警发送到 clients 中的全部接收者，队列目录为临时目录，重试间隔足够长，测试期间不会重新投递
+func newTestConfig(t *testing.T, clients ...string) *config.AppConfig {
+	t.Helper()
+	cfg := &config.AppConfig{
+		Clients:   clients,
+		Notifiers: make(map[string]config.NotifierConfig),
+		Server:    config.ServerConfig{SyncTimeout: 5},
+		Queue: config.QueueConfig{
+			DataDir:       t.TempDir(),
+			RetryInterval: 3600,
+			MaxAttempts:   10,
+			MaxPending:    100,
+			Workers:       4,
+		},
+	}
+	for _, client := range clients {
+		cfg.Notifiers[client] = config.NotifierConfig{}
+	}
+	return cfg
+}
+
+// newTestDelivery 创建并启动投递服务，测试结束时停止
+func newTestDelivery(t *testing.T, cfg *config.AppConfig, notifiers ...*stubNotifier) *DeliveryService {
+	t.Helper()
+	byName := make(map[string]notifier.Notifier, len(notifiers))
+	for _, n := range notifiers {
+		byName[n.name] = n
+	}
+	delivery, err := NewDeliveryService(cfg, byName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery.Start()
+	t.Cleanup(delivery.Stop)
+	return delivery
+}
+
+func testAlertData(names ...string) template.Data {
+	data := template.Data{Status: "firing"}
+	for _, name := range names {
+		data.Alerts = append(data.Alerts, template.Alert{
+			Status: "firing",
+			Labels: template.KV{"alertname": name, "severity": "warning"},
+		})
+	}
+	return data
+}
+
+// postAlerts 调用告警接口，返回 HTTP 状态码和解析后的响应体
+func postAlerts(t *testing.T, delivery *DeliveryService, cfg *config.AppConfig, data template.Data) (int, *DeliveryReport) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhook-alert", GinAlertHandler(delivery, cfg))
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/webhook-alert", bytes.NewReader(body)))
+
+	report := &DeliveryReport{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), report); err != nil {
+		t.Fatalf("response body %q is not a DeliveryReport: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, report
+}
+
+func TestAlertHandlerAsyncAccepted(t *testing.T) {
+	cfg := newTestConfig(t, "ops")
+	cfg.Server.Async = true
+	// 发送阻塞，异步模式不等待投递结果
+	ops := &stubNotifier{name: "ops", block: make(chan struct{})}
+	defer close(ops.block)
+	delivery := newTestDelivery(t, cfg, ops)
+
+	code, report := postAlerts(t, delivery, cfg, testAlertData("HighCPU"))
+	if code != http.StatusAccepted || report.Status != ReportAccepted {
+		t.Fatalf("response = %d %+v, want 202 accepted", code, report)
+	}
+	if report.Alerts != 1 || len(report.Receivers) != 0 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestAlertHandlerQueueFull(t *testing.T) {
+	cfg := newTestConfig(t, "ops", "dev")
+	// 两个接收者各占一条未完成投递，超过上限
+	cfg.Queue.MaxPending = 1
+	delivery := newTestDelivery(t, cfg, &stubNotifier{name: "ops"}, &stubNotifier{name: "dev"})
+
+	code, report := postAlerts(t, delivery, cfg, testAlertData("HighCPU"))
+	if code != http.StatusServiceUnavailable || report.Status != ReportRejected {
+		t.Fatalf("response = %d %+v, want 503 rejected", code, report)
+	}
+
+	receivers, groups, _ := routeAlerts(cfg, testAlertData("HighCPU"))
+	if _, err := delivery.Submit(receivers, groups); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit err = %v, want ErrQueueFull", err)
+	}
+	if pending := delivery.queue.Len(); pending != 0 {
+		t.Errorf("queue length = %d, rejected request must not enqueue any receiver", pending)
+	}
+}
+
+// receiverResults 按接收者名称索引响应中的接收者结果
+func receiverResults(report *DeliveryReport) map[string]ReceiverResult {
+	results := make(map[string]ReceiverResult, len(report.Receivers))
+	for _, result := range report.Receivers {
+		results[result.Receiver] = result
+	}
+	return results
+}
+
+func TestAlertHandlerPartialSuccess(t *testing.T) {
+	cfg := newTestConfig(t, "ops", "dev")
+	vendorErr := &notifier.SendError{StatusCode: http.StatusOK, Err: &notifier.VendorError{Code: 93000, Message: "invalid webhook url"}}
+	delivery := newTestDelivery(t, cfg, &stubNotifier{name: "ops"}, &stubNotifier{name: "dev", err: vendorErr})
+
+	code, report := postAlerts(t, delivery, cfg, testAlertData("HighCPU", "DiskFull"))
+	if code != http.StatusInternalServerError || report.Status != ReportPartial || report.Alerts != 2 {
+		t.Fatalf("response = %d %+v, want 500 partial", code, report)
+	}
+
+	results := receiverResults(report)
+	if ops := results["ops"]; ops.Status != ReceiverSuccess || ops.Alerts != 2 || ops.DeliveredBatches != 2 || ops.TotalBatches != 2 || ops.Error != "" {
+		t.Errorf("ops = %+v", ops)
+	}
+	// 永久失败直接进入死信，不再保留在队列中
+	dev := results["dev"]
+	if dev.Status != ReceiverFailed || dev.DeliveredBatches != 0 || dev.TotalBatches != 2 || dev.VendorCode != 93000 || dev.HTTPStatus != 0 || dev.Queued || dev.Error == "" {
+		t.Errorf("dev = %+v", dev)
+	}
+}
+
+func TestAlertHandlerAllFailed(t *testing.T) {
+	cfg := newTestConfig(t, "ops", "dev")
+	serverErr := &notifier.SendError{StatusCode: http.StatusBadRequest, Err: errors.New("bad request")}
+	delivery := newTestDelivery(t, cfg, &stubNotifier{name: "ops", err: serverErr}, &stubNotifier{name: "dev", err: serverErr})
+
+	code, report := postAlerts(t, delivery, cfg, testAlertData("HighCPU"))
+	if code != http.StatusInternalServerError || report.Status != ReportFailed || len(report.Receivers) != 2 {
+		t.Fatalf("response = %d %+v, want 500 failed", code, report)
+	}
+	for _, result := range report.Receivers {
+		if result.Status != ReceiverFailed || result.HTTPStatus != http.StatusBadRequest || result.Queued {
+			t.Errorf("%s = %+v", result.Receiver, result)
+		}
+	}
+}
+
+func TestAlertHandlerRetryingAccepted(t *testing.T) {
+	cfg := newTestConfig(t, "ops", "dev")
+	retryable := &notifier.SendError{StatusCode: http.StatusServiceUnavailable, Retryable: true, Err: errors.New("unavailable")}
+	delivery := newTestDelivery(t, cfg, &stubNotifier{name: "ops"}, &stubNotifier{name: "dev", err: retryable})
+
+	// 可重试的失败保留在投递队列中，整体按已接收返回 202
+	code, report := postAlerts(t, delivery, cfg, testAlertData("HighCPU"))
+	if code != http.StatusAccepted || report.Status != ReportAccepted {
+		t.Fatalf("response = %d %+v, want 202 accepted", code, report)
+	}
+	if dev := receiverResults(report)["dev"]; dev.Status != ReceiverFailed || !dev.Queued || dev.HTTPStatus != http.StatusServiceUnavailable {
+		t.Errorf("dev = %+v", dev)
+	}
+}
+
+func TestAlertHandlerSyncTimeout(t *testing.T) {
+	cfg := newTestConfig(t, "ops", "slow")
+	cfg.Server.SyncTimeout = 1
+	slow := &stubNotifier{name: "slow", block: make(chan struct{})}
+	defer close(slow.block)
+	delivery := newTestDelivery(t, cfg, &stubNotifier{name: "ops"}, slow)
+
+	code, report := postAlerts(t, delivery, cfg, testAlertData("HighCPU", "DiskFull"))
+	if code != http.StatusAccepted || report.Status != ReportAccepted {
+		t.Fatalf("response = %d %+v, want 202 accepted", code, report)
+	}
+	results := receiverResults(report)
+	if ops := results["ops"]; ops.Status != ReceiverSuccess {
+		t.Errorf("ops = %+v", ops)
+	}
+	if pending := results["slow"]; pending.Status != ReceiverPending || pending.Alerts != 2 || !pending.Queued || pending.TotalBatches != 0 {
+		t.Errorf("slow = %+v", pending)
+	}
+}
+
+func TestAlertHandlerUnconfiguredReceiverFailed(t *testing.T) {
+	cfg := newTestConfig(t, "ops", "ghost")
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	cfg.Audit.File = audit.FileConfig{Enabled: true, Path: auditPath}
+	// ghost 出现在配置中但没有创建通知器
+	delivery := newTestDelivery(t, cfg, &stubNotifier{name: "ops"})
+
+	code, report := postAlerts(t, delivery, cfg, testAlertData("HighCPU"))
+	if code != http.StatusInternalServerError || report.Status != ReportPartial {
+		t.Fatalf("response = %d %+v, want 500 partial", code, report)
+	}
+	if ghost := receiverResults(report)["ghost"]; ghost.Status != ReceiverFailed || ghost.Alerts != 1 || ghost.Queued || ghost.Error == "" {
+		t.Errorf("ghost = %+v", ghost)
+	}
+	if pending := delivery.queue.Len(); pending != 0 {
+		t.Errorf("queue length = %d, unconfigured receiver must not be enqueued", pending)
+	}
+
+	delivery.auditor.Close()
+	file, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	outcomes := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record audit.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		outcomes[record.Receiver] = record.Outcome
+	}
+	if outcomes["ghost"] != audit.OutcomeUnconfigured || outcomes["ops"] != audit.OutcomeDelivered {
+		t.Errorf("audit outcomes = %v", outcomes)
+	}
+}
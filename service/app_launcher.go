@@ -36,7 +36,7 @@ func (app *AppLauncher) Run() {
 
 	// 4. 启动服务器
 	console.Success("[Running]", "服务已启动，端口信息: "+config.ServerPort)
-	if err := app.serverManager.StartWebhookServer(config.ServerPort); err != nil {
+	if err := app.serverManager.StartWebhookServer(config.ServerPort, app.serviceManager.DeliveryService()); err != nil {
 		log.Fatalf("Webhook 服务启动失败: %v", err)
 	}
 }
@@ -54,6 +54,9 @@ func (app *AppLauncher) testClientsConnection() {
 
 // initializeServices 初始化所有服务
 func (app *AppLauncher) initializeServices() {
+	// 初始化投递服务，其他服务依赖它发送告警
+	app.serviceManager.InitializeDelivery()
+
 	// 初始化大流量告警服务
 	app.serviceManager.InitializeTrafficAlert()
 }
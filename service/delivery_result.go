@@ -0,0 +1,162 @@
+package service
+
+import (
+	"alert-webhook/notifier"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 接收者投递状态
+const (
+	ReceiverSuccess = "success"
+	ReceiverPartial = "partial"
+	ReceiverFailed  = "failed"
+	// 等待首次投递结果超时，告警仍在投递队列中
+	ReceiverPending = "pending"
+)
+
+// 整体处理状态
+const (
+	ReportSuccess  = "success"
+	ReportPartial  = "partial"
+	ReportFailed   = "failed"
+	ReportAccepted = "accepted"
+	ReportFiltered = "filtered"
+	ReportRejected = "rejected"
+)
+
+// ReceiverResult 单个接收者的投递结果
+type ReceiverResult struct {
+	Receiver string `json:"receiver"`
+	Status   string `json:"status"`
+	// 发往该接收者的告警数
+	Alerts int `json:"alerts"`
+	// 成功发送的批次数和总批次数
+	DeliveredBatches int `json:"delivered_batches"`
+	TotalBatches     int `json:"total_batches"`
+	// HTTP 发送次数，包含重试
+	Attempts  int    `json:"attempts"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	// 平台业务错误码，仅平台以 HTTP 200 返回错误时有值
+	VendorCode int `json:"vendor_code,omitempty"`
+	// HTTP 状态码，仅平台返回非 2xx 时有值
+	HTTPStatus int `json:"http_status,omitempty"`
+	// 失败的告警是否仍保留在投递队列中等待重试
+	Queued bool `json:"queued,omitempty"`
+}
+
+// newReceiverResult 根据一次投递的结果生成接收者结果
+func newReceiverResult(receiver string, alerts, delivered, total, attempts int, latency time.Duration, err error) ReceiverResult {
+	result := ReceiverResult{
+		Receiver:         receiver,
+		Status:           ReceiverSuccess,
+		Alerts:           alerts,
+		DeliveredBatches: delivered,
+		TotalBatches:     total,
+		Attempts:         attempts,
+		LatencyMs:        latency.Milliseconds(),
+	}
+	if err == nil {
+		return result
+	}
+
+	result.Status = ReceiverFailed
+	if delivered > 0 {
+		result.Status = ReceiverPartial
+	}
+	result.Error = err.Error()
+
+	var vendorErr *notifier.VendorError
+	if errors.As(err, &vendorErr) {
+		result.VendorCode = vendorErr.Code
+	}
+	var sendErr *notifier.SendError
+	if errors.As(err, &sendErr) && sendErr.StatusCode != http.StatusOK {
+		result.HTTPStatus = sendErr.StatusCode
+	}
+	return result
+}
+
+// newPendingResult 未等到首次投递结果的接收者结果
+func newPendingResult(receiver string, alerts int) ReceiverResult {
+	return ReceiverResult{
+		Receiver: receiver,
+		Status:   ReceiverPending,
+		Alerts:   alerts,
+		Queued:   true,
+	}
+}
+
+// DeliveryReport 一次告警请求的处理结果，汇总各接收者的投递结果，可并发写入
+type DeliveryReport struct {
+	Status    string           `json:"status"`
+	Message   string           `json:"message"`
+	Alerts    int              `json:"alerts"`
+	Receivers []ReceiverResult `json:"receivers"`
+
+	mu sync.Mutex
+}
+
+// NewDeliveryReport 创建处理结果
+func NewDeliveryReport(alerts int) *DeliveryReport {
+	return &DeliveryReport{
+		Alerts:    alerts,
+		Receivers: make([]ReceiverResult, 0),
+	}
+}
+
+// Add 记录一个接收者的投递结果
+func (r *DeliveryReport) Add(result ReceiverResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Receivers = append(r.Receivers, result)
+}
+
+// Finish 根据各接收者结果计算整体状态，返回对应的 HTTP 状态码
+func (r *DeliveryReport) Finish() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 投递失败但仍保留在投递队列中的接收者与未等到结果的接收者一样由队列继续投递
+	// 只有进入死信或未能写入队列的失败才返回 5xx，避免 Alertmanager 重复推送与队列重投叠加
+	failed, pending := 0, 0
+	for _, result := range r.Receivers {
+		switch {
+		case result.Status == ReceiverSuccess:
+		case result.Queued:
+			pending++
+		default:
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0 && pending > 0:
+		// 告警已写入投递队列，返回 202 避免 Alertmanager 重复推送
+		r.Status = ReportAccepted
+		r.Message = "部分接收者仍在投递中，由投递队列继续完成"
+		return http.StatusAccepted
+	case failed == 0:
+		r.Status = ReportSuccess
+		r.Message = "告警已成功发送到所有客户端"
+		return http.StatusOK
+	case failed == len(r.Receivers):
+		r.Status = ReportFailed
+		r.Message = "所有客户端发送失败"
+	default:
+		r.Status = ReportPartial
+		r.Message = "部分客户端发送失败"
+	}
+	return http.StatusInternalServerError
+}
+
+// Reply 设置不涉及投递的整体状态，例如请求无效、告警被过滤、异步接收
+func (r *DeliveryReport) Reply(status, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Status = status
+	r.Message = message
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"alert-webhook/audit"
+	"alert-webhook/config"
+	"alert-webhook/notifier"
+	"alert-webhook/queue"
+	"alert-webhook/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// ErrQueueFull 投递队列已满，调用方应稍后重试
+var ErrQueueFull = errors.New("投递队列已满")
+
+// DeliveryService 投递服务，告警先写入持久化队列，再由每个接收者的工作协程按顺序投递
+// 投递失败的告警保留在队列中定时重试，进程重启后继续投递，永久失败的进入死信
+type DeliveryService struct {
+	queue     *queue.Queue
+	notifiers map[string]notifier.Notifier
+	config    config.QueueConfig
+	// 审计记录，未配置时为 nil
+	auditor *audit.Auditor
+
+	mu      sync.Mutex
+	waiters map[string]chan ReceiverResult
+
+	// 限制同时投递的协程数
+	slots chan struct{}
+
+	// 停止时取消进行中的重试等待
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewDeliveryService 打开持久化队列并创建投递服务
+func NewDeliveryService(cfg *config.AppConfig, notifiers map[string]notifier.Notifier) (*DeliveryService, error) {
+	auditor, err := audit.New(cfg.Audit)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := queue.Open(cfg.Queue.DataDir)
+	if err != nil {
+		auditor.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DeliveryService{
+		ctx:       ctx,
+		cancel:    cancel,
+		queue:     q,
+		notifiers: notifiers,
+		config:    cfg.Queue,
+		auditor:   auditor,
+		waiters:   make(map[string]chan ReceiverResult),
+		slots:     make(chan struct{}, cfg.Queue.Workers),
+		stopChan:  make(chan struct{}),
+	}, nil
+}
+
+// Start 为每个接收者启动投递协程，并重新投递上次未完成的告警
+func (d *DeliveryService) Start() {
+	// 接收者已从配置中删除的投递无法完成，直接进入死信
+	for _, receiver := range d.queue.Receivers() {
+		if _, ok := d.notifiers[receiver]; ok {
+			continue
+		}
+		for {
+			item, ok := d.queue.Peek(receiver)
+			if !ok {
+				break
+			}
+			reason := fmt.Errorf("接收者 %s 未配置", receiver)
+			if err := d.queue.DeadLetter(item, reason.Error()); err != nil {
+				log.Printf("[%s] 写入死信失败: %v", receiver, err)
+				break
+			}
+			d.auditor.Record(audit.NewRecords(receiver, item.Data, audit.OutcomeDeadLetter, item.Attempts, reason)...)
+		}
+	}
+
+	if pending := d.queue.Len(); pending > 0 {
+		log.Printf("投递队列中有 %d 条未完成的告警，将重新投递", pending)
+	}
+
+	for name, n := range d.notifiers {
+		d.wg.Add(1)
+		go d.worker(name, n)
+	}
+}
+
+// Stop 停止投递协程并关闭队列，未完成的投递在下次启动时继续
+func (d *DeliveryService) Stop() {
+	close(d.stopChan)
+	d.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		log.Println("等待投递协程退出超时，未完成的投递将在下次启动时继续")
+	}
+
+	if err := d.queue.Close(); err != nil {
+		log.Printf("关闭投递队列失败: %v", err)
+	}
+	d.auditor.Close()
+
+	// 关闭持有长连接的接收者，如消息总线客户端
+	for name, n := range d.notifiers {
+		if closer, ok := n.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Printf("[%s] 关闭连接失败: %v", name, err)
+			}
+		}
+	}
+}
+
+// Submit 将每个接收者的告警写入队列，返回每个接收者首次投递结果的 channel，未配置通知器的接收者直接返回失败结果
+// 队列未完成的投递超过上限时不写入任何告警，返回 ErrQueueFull
+func (d *DeliveryService) Submit(receivers []string, groups map[string]template.Data) (map[string]<-chan ReceiverResult, error) {
+	results := make(map[string]<-chan ReceiverResult, len(receivers))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.queue.Len()+len(receivers) > d.config.MaxPending {
+		return results, ErrQueueFull
+	}
+
+	for _, receiver := range receivers {
+		ch := make(chan ReceiverResult, 1)
+		results[receiver] = ch
+
+		// 没有可用通知器的接收者无法投递，直接记为失败，不写入队列
+		if _, ok := d.notifiers[receiver]; !ok {
+			err := fmt.Errorf("接收者 %s 未配置", receiver)
+			log.Printf("[%s] 告警无法投递: %v", receiver, err)
+			d.auditor.Record(audit.NewRecords(receiver, groups[receiver], audit.OutcomeUnconfigured, 0, err)...)
+			ch <- newReceiverResult(receiver, len(groups[receiver].Alerts), 0, 0, 0, 0, err)
+			continue
+		}
+
+		item, err := d.queue.Enqueue(receiver, groups[receiver])
+		if err != nil {
+			return results, fmt.Errorf("[%s] 告警写入投递队列失败: %w", receiver, err)
+		}
+		d.waiters[item.ID] = ch
+	}
+	return results, nil
+}
+
+// DeadLetters 返回全部死信记录
+func (d *DeliveryService) DeadLetters() ([]queue.DeadLetter, error) {
+	return d.queue.DeadLetters()
+}
+
+// worker 按顺序投递一个接收者的告警，投递失败时等待重试间隔后再次投递队首告警
+func (d *DeliveryService) worker(receiver string, n notifier.Notifier) {
+	defer d.wg.Done()
+
+	for {
+		item, ok := d.queue.Peek(receiver)
+		if !ok {
+			select {
+			case <-d.queue.Wait(receiver):
+				continue
+			case <-d.stopChan:
+				return
+			}
+		}
+
+		// 占用投递名额，限制同时投递的协程数
+		select {
+		case d.slots <- struct{}{}:
+		case <-d.stopChan:
+			return
+		}
+		done := d.deliver(n, item)
+		<-d.slots
+
+		if done {
+			continue
+		}
+
+		select {
+		case <-time.After(time.Duration(d.config.RetryInterval) * time.Second):
+		case <-d.stopChan:
+			return
+		}
+	}
+}
+
+// deliver 投递一次，返回该告警是否已离开队列（成功或进入死信）
+func (d *DeliveryService) deliver(n notifier.Notifier, item *queue.Item) bool {
+	ctx, attempts := notifier.WithAttemptCounter(d.ctx)
+	start := time.Now()
+	delivered, sent, total, err := notifier.Dispatch(ctx, n, item.Data, item.Delivered)
+	item.Attempts++
+	item.Delivered = delivered
+
+	result := newReceiverResult(item.Receiver, len(item.Data.Alerts), sent, total,
+		int(attempts.Load()), time.Since(start), err)
+	defer func() {
+		d.notify(item.ID, result)
+	}()
+
+	if err == nil {
+		if err := d.queue.Ack(item.ID); err != nil {
+			log.Printf("[%s] 确认投递失败: %v", item.Receiver, err)
+		}
+		d.audit(item, audit.OutcomeDelivered, nil)
+		return true
+	}
+
+	item.LastError = err.Error()
+	if !notifier.IsRetryable(err) || item.Attempts >= d.config.MaxAttempts {
+		log.Printf("[%s] 告警投递 %d 次后永久失败，写入死信: %v", item.Receiver, item.Attempts, err)
+		if err := d.queue.DeadLetter(item, item.LastError); err != nil {
+			log.Printf("[%s] 写入死信失败: %v", item.Receiver, err)
+			return false
+		}
+		d.audit(item, audit.OutcomeDeadLetter, err)
+		return true
+	}
+
+	// 部分消息永久失败时，这些告警单独进入死信，其余告警继续重试
+	var dispatchErr *notifier.DispatchError
+	var permanent map[string]bool
+	if errors.As(err, &dispatchErr) && len(dispatchErr.Permanent) > 0 {
+		permanent = d.deadLetterAlerts(item, dispatchErr.Permanent, dispatchErr.PermanentErr())
+	}
+	d.auditor.Record(audit.NewRecords(item.Receiver, filterAlerts(item.Data, permanent, false), audit.OutcomeRetrying, item.Attempts, err)...)
+
+	log.Printf("[%s] 告警第 %d 次投递失败，%d 秒后重新投递: %v", item.Receiver, item.Attempts, d.config.RetryInterval, err)
+	result.Queued = true
+	if err := d.queue.Progress(item); err != nil {
+		log.Printf("[%s] 记录投递进度失败: %v", item.Receiver, err)
+	}
+	return false
+}
+
+// deadLetterAlerts 将所在消息永久失败的告警写入死信并标记为已发送，重新投递时不再发送，返回已写入死信的告警指纹
+func (d *DeliveryService) deadLetterAlerts(item *queue.Item, fingerprints []string, reason error) map[string]bool {
+	permanent := make(map[string]bool, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		permanent[fingerprint] = true
+	}
+
+	letter := *item
+	letter.Data = filterAlerts(item.Data, permanent, true)
+	letter.Delivered = nil
+	letter.LastError = reason.Error()
+	if err := d.queue.AppendDeadLetter(&letter, letter.LastError); err != nil {
+		log.Printf("[%s] 写入死信失败，%d 个告警将继续重试: %v", item.Receiver, len(fingerprints), err)
+		return nil
+	}
+
+	log.Printf("[%s] %d 个告警永久失败，写入死信，其余告警继续重试: %v", item.Receiver, len(fingerprints), reason)
+	item.Delivered = append(item.Delivered, fingerprints...)
+	d.auditor.Record(audit.NewRecords(item.Receiver, letter.Data, audit.OutcomeDeadLetter, item.Attempts, reason)...)
+	return permanent
+}
+
+// filterAlerts 返回指纹在 fingerprints 中（include 为 true）或不在其中（include 为 false）的告警
+func filterAlerts(data template.Data, fingerprints map[string]bool, include bool) template.Data {
+	filtered := data
+	filtered.Alerts = nil
+	for _, alert := range data.Alerts {
+		if fingerprints[utils.AlertFingerprint(alert)] == include {
+			filtered.Alerts = append(filtered.Alerts, alert)
+		}
+	}
+	return filtered
+}
+
+// audit 为一次投递中的每个告警写入审计记录
+func (d *DeliveryService) audit(item *queue.Item, outcome string, err error) {
+	d.auditor.Record(audit.NewRecords(item.Receiver, item.Data, outcome, item.Attempts, err)...)
+}
+
+// AuditUnrouted 为通过过滤规则但没有发往任何接收者的告警写入审计记录
+func (d *DeliveryService) AuditUnrouted(data template.Data) {
+	d.auditor.Record(audit.NewRecords("", data, audit.OutcomeUnrouted, 0, nil)...)
+}
+
+// notify 将首次投递结果通知给等待的请求
+func (d *DeliveryService) notify(id string, result ReceiverResult) {
+	d.mu.Lock()
+	ch, ok := d.waiters[id]
+	delete(d.waiters, id)
+	d.mu.Unlock()
+
+	if ok {
+		ch <- result
+	}
+}
+
+// GinDeadLetterHandler 返回全部死信记录，用于排查永久失败的投递
+func GinDeadLetterHandler(delivery *DeliveryService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		letters, err := delivery.DeadLetters()
+		if err != nil {
+			log.Printf("读取死信记录失败: %v", err)
+			c.String(http.StatusInternalServerError, "读取死信记录失败")
+			return
+		}
+		c.JSON(http.StatusOK, letters)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveryWorkersBoundConcurrency(t *testing.T) {
+	cfg := newTestConfig(t, "a", "b", "c")
+	cfg.Queue.Workers = 2
+	block := make(chan struct{})
+	notifiers := []*stubNotifier{
+		{name: "a", block: block},
+		{name: "b", block: block},
+		{name: "c", block: block},
+	}
+	delivery := newTestDelivery(t, cfg, notifiers...)
+
+	receivers, groups, _ := routeAlerts(cfg, testAlertData("HighCPU"))
+	results, err := delivery.Submit(receivers, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 等待两个接收者占满投递名额，第三个接收者应一直等待
+	inflight := func() int32 {
+		var total int32
+		for _, n := range notifiers {
+			total += n.inflight.Load()
+		}
+		return total
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for inflight() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := inflight(); got != 2 {
+		t.Fatalf("in-flight deliveries = %d, want workers limit 2", got)
+	}
+
+	close(block)
+	for _, receiver := range receivers {
+		select {
+		case result := <-results[receiver]:
+			if result.Status != ReceiverSuccess {
+				t.Errorf("[%s] result = %+v", receiver, result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("[%s] timed out waiting for delivery", receiver)
+		}
+	}
+	for _, n := range notifiers {
+		if n.sent.Load() != 1 {
+			t.Errorf("[%s] sent = %d, want 1", n.name, n.sent.Load())
+		}
+	}
+}
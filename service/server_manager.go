@@ -20,9 +20,10 @@ func NewServerManager() *ServerManager {
 }
 
 // StartWebhookServer 启动webhook服务器
-func (sm *ServerManager) StartWebhookServer(addr string) error {
+func (sm *ServerManager) StartWebhookServer(addr string, delivery *DeliveryService) error {
 	router := gin.New()
-	router.POST("/webhook-alert", GinAlertHandler(config.Notifiers, config.EnabledClients, config.GlobalConfig))
+	router.POST("/webhook-alert", GinAlertHandler(delivery, config.GlobalConfig))
+	router.GET("/dead-letters", GinDeadLetterHandler(delivery))
 
 	sm.server = &http.Server{
 		Addr:    addr,
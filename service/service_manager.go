@@ -8,6 +8,7 @@ import (
 
 // ServiceManager 服务管理器
 type ServiceManager struct {
+	deliveryService     *DeliveryService
 	clickhouseService   *ClickHouseService
 	trafficAlertService *TrafficAlertService
 }
@@ -17,6 +18,22 @@ func NewServiceManager() *ServiceManager {
 	return &ServiceManager{}
 }
 
+// InitializeDelivery 初始化投递服务，打开持久化队列并重新投递未完成的告警
+func (sm *ServiceManager) InitializeDelivery() {
+	var err error
+	sm.deliveryService, err = NewDeliveryService(config.GlobalConfig, config.Notifiers)
+	if err != nil {
+		log.Fatalf("投递服务初始化失败: %v", err)
+	}
+	sm.deliveryService.Start()
+	console.Success("[Success]", "投递服务启动成功，队列目录: "+config.GlobalConfig.Queue.DataDir)
+}
+
+// DeliveryService 返回投递服务
+func (sm *ServiceManager) DeliveryService() *DeliveryService {
+	return sm.deliveryService
+}
+
 // InitializeTrafficAlert 初始化大流量告警服务
 func (sm *ServiceManager) InitializeTrafficAlert() {
 	if !config.GlobalConfig.TrafficAlert.Enabled {
@@ -50,8 +67,7 @@ func (sm *ServiceManager) InitializeTrafficAlert() {
 	sm.trafficAlertService = NewTrafficAlertService(
 		sm.clickhouseService,
 		config.GlobalConfig,
-		config.Notifiers,
-		config.EnabledClients,
+		sm.deliveryService,
 	)
 	sm.trafficAlertService.Start()
 	console.Success("[Success]", "大流量告警服务启动成功")
@@ -75,6 +91,12 @@ func (sm *ServiceManager) Shutdown() {
 			log.Println("ClickHouse连接已关闭")
 		}
 	}
+
+	// 最后停止投递服务，未完成的投递保留在队列中
+	if sm.deliveryService != nil {
+		sm.deliveryService.Stop()
+		log.Println("投递服务已停止")
+	}
 }
 
 // IsTrafficAlertEnabled 检查大流量告警是否已启用
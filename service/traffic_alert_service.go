@@ -2,7 +2,6 @@ package service
 
 import (
 	"alert-webhook/config"
-	"alert-webhook/utils"
 	"fmt"
 	"log"
 	"sync"
@@ -15,19 +14,17 @@ import (
 type TrafficAlertService struct {
 	clickhouseService *ClickHouseService
 	config            *config.AppConfig
-	notifiers         map[string]string
-	enabledClients    []string
+	delivery          *DeliveryService
 	stopChan          chan bool
 	wg                sync.WaitGroup
 }
 
 // NewTrafficAlertService 创建流量告警服务实例
-func NewTrafficAlertService(clickhouseService *ClickHouseService, cfg *config.AppConfig, notifiers map[string]string, enabledClients []string) *TrafficAlertService {
+func NewTrafficAlertService(clickhouseService *ClickHouseService, cfg *config.AppConfig, delivery *DeliveryService) *TrafficAlertService {
 	return &TrafficAlertService{
 		clickhouseService: clickhouseService,
 		config:            cfg,
-		notifiers:         notifiers,
-		enabledClients:    enabledClients,
+		delivery:          delivery,
 		stopChan:          make(chan bool),
 	}
 }
@@ -115,22 +112,16 @@ func (t *TrafficAlertService) generateAndSendAlert(stat TrafficStats) {
 		Alerts: []template.Alert{alert},
 	}
 
-	// 发送到所有启用的客户端
-	var wg sync.WaitGroup
-	for _, client := range t.enabledClients {
-		webhookURL, ok := t.notifiers[client]
-		if !ok {
-			log.Printf("客户端 %s 未配置", client)
-			continue
-		}
-
-		wg.Add(1)
-		go func(client, url string) {
-			defer wg.Done()
-			t.sendAlert(client, url, data)
-		}(client, webhookURL)
+	// 按路由树写入投递队列，由投递服务发送到匹配的接收者
+	receivers, groups, unrouted := routeAlerts(t.config, data)
+	if len(unrouted.Alerts) > 0 {
+		t.delivery.AuditUnrouted(unrouted)
+	}
+	if _, err := t.delivery.Submit(receivers, groups); err != nil {
+		log.Printf("大流量告警写入投递队列失败: %v", err)
+		return
 	}
-	wg.Wait()
+	log.Printf("大流量告警已写入投递队列，接收者: %v", receivers)
 }
 
 // createTrafficAlert 创建流量告警对象
@@ -202,69 +193,6 @@ func (t *TrafficAlertService) createTrafficAlert(stat TrafficStats, largeRequest
 	}
 }
 
-// sendAlert 发送告警到指定客户端
-func (t *TrafficAlertService) sendAlert(client, webhookURL string, data template.Data) {
-	log.Printf("向 %s 发送大流量告警", client)
-
-	// 企业微信需要特殊处理消息长度限制
-	if client == "wechat" {
-		// 将告警分批处理
-		alertBatches := utils.SplitWeChatAlerts(data)
-		log.Printf("[%s] 大流量告警分为 %d 批发送", client, len(alertBatches))
-
-		for i, batchData := range alertBatches {
-			message := WeChatMessage{
-				MsgType: "markdown",
-				Markdown: MarkdownMessage{
-					Content: utils.AlertFormatWechat(batchData),
-				},
-			}
-
-			if err := SendAlert(client, webhookURL, message); err != nil {
-				log.Printf("[%s] 第 %d 批大流量告警发送失败: %v", client, i+1, err)
-			} else {
-				log.Printf("[%s] 第 %d 批大流量告警发送成功", client, i+1)
-			}
-
-			// 批次之间添加小延迟
-			if i < len(alertBatches)-1 {
-				time.Sleep(200 * time.Millisecond)
-			}
-		}
-		return
-	}
-
-	// 其他客户端正常处理
-	var message interface{}
-
-	switch client {
-	case "dingtalk":
-		message = DingTalkMessage{
-			MsgType: "markdown",
-			Markdown: DingTalkMarkdown{
-				Title: "大流量告警",
-				Text:  utils.AlertFormatDingtalk(data),
-			},
-		}
-	case "feishu":
-		message = FeishuMessage{
-			MsgType: "text",
-			Content: FeishuContent{
-				Text: utils.AlertFormatFeishu(data),
-			},
-		}
-	default:
-		log.Printf("未知客户端类型: %s", client)
-		return
-	}
-
-	if err := SendAlert(client, webhookURL, message); err != nil {
-		log.Printf("[%s] 大流量告警发送失败: %v", client, err)
-	} else {
-		log.Printf("[%s] 大流量告警发送成功", client)
-	}
-}
-
 // formatBytes 格式化字节数为可读格式
 func formatBytes(bytes int64) string {
 	const unit = 1024
@@ -3,57 +3,71 @@ package utils
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
 )
 
-func AlertFormatFeishu(data template.Data) string {
+// AlertFormatFeishu 飞书内置消息格式，时间按 tf 配置的时区和格式显示
+// 飞书文本消息不渲染 Markdown，因此只使用纯文本；同一批中的告警按自身状态分为告警中和已恢复两部分
+func AlertFormatFeishu(data template.Data, tf TimeFormat) string {
 	var builder strings.Builder
-	alertCount := len(data.Alerts)
+	now := time.Now()
+	firing, resolved := SplitByStatus(data)
 
-	if data.Status == "firing" {
-		builder.WriteString("**🔥 Prometheus告警通知**\n")
+	if len(firing) > 0 {
+		builder.WriteString("🔥 Prometheus告警通知\n")
 		builder.WriteString("请关注告警信息，相关人员请注意\n")
-		builder.WriteString("> **状态:** 告警中\n")
-		for i, alert := range data.Alerts {
-			if alertCount > 1 && i > 0 {
-				builder.WriteString("> ---\n")
+		builder.WriteString("状态: 告警中\n")
+		for i, alert := range firing {
+			if i > 0 {
+				builder.WriteString("---\n")
 			}
 			severity := alert.Labels["severity"]
-			builder.WriteString(fmt.Sprintf("> **告警名称:** %s\n", alert.Labels["alertname"]))
-			builder.WriteString(fmt.Sprintf("> **级别:** %s\n", MapSeverity(severity)))
-			builder.WriteString(fmt.Sprintf("> **实例:** %s\n", alert.Labels["instance"]))
-			builder.WriteString(fmt.Sprintf("> **摘要:** %s\n", alert.Annotations["summary"]))
-			builder.WriteString(fmt.Sprintf("> **描述:** %s\n", alert.Annotations["description"]))
-			builder.WriteString(fmt.Sprintf("> **触发时间:** %s\n", alert.StartsAt.Format("2006-01-02 15:04:05")))
+			builder.WriteString(fmt.Sprintf("告警名称: %s\n", alert.Labels["alertname"]))
+			builder.WriteString(fmt.Sprintf("级别: %s\n", MapSeverity(severity)))
+			builder.WriteString(fmt.Sprintf("实例: %s\n", alert.Labels["instance"]))
+			builder.WriteString(fmt.Sprintf("摘要: %s\n", alert.Annotations["summary"]))
+			builder.WriteString(fmt.Sprintf("描述: %s\n", alert.Annotations["description"]))
+			builder.WriteString(fmt.Sprintf("触发时间: %s\n", tf.Format(alert.StartsAt)))
+			builder.WriteString(fmt.Sprintf("已持续: %s\n", FormatDuration(AlertDuration(alert, data.Status, now))))
 		}
-	} else if data.Status == "resolved" {
-		builder.WriteString("**✅ Prometheus告警恢复**\n")
-		builder.WriteString("> **状态:** 已恢复\n")
-		for i, alert := range data.Alerts {
-			if alertCount > 1 && i > 0 {
-				builder.WriteString("> ---\n")
+	}
+
+	if len(resolved) > 0 {
+		if len(firing) > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString("✅ Prometheus告警恢复\n")
+		builder.WriteString("状态: 已恢复\n")
+		for i, alert := range resolved {
+			if i > 0 {
+				builder.WriteString("---\n")
 			}
-			builder.WriteString(fmt.Sprintf("> **告警名称:** %s\n", alert.Labels["alertname"]))
-			builder.WriteString(fmt.Sprintf("> **恢复时间:** %s\n", alert.EndsAt.Format("2006-01-02 15:04:05")))
+			builder.WriteString(fmt.Sprintf("告警名称: %s\n", alert.Labels["alertname"]))
+			builder.WriteString(fmt.Sprintf("恢复时间: %s\n", tf.Format(alert.EndsAt)))
+			builder.WriteString(fmt.Sprintf("持续时间: %s\n", FormatDuration(AlertDuration(alert, data.Status, now))))
 		}
 	}
 	return builder.String()
 }
 
-func AlertFormatDingtalk(data template.Data) string {
+// AlertFormatDingtalk 钉钉内置消息格式，时间按 tf 配置的时区和格式显示
+// 同一批中的告警按自身状态分为告警中和已恢复两部分
+func AlertFormatDingtalk(data template.Data, tf TimeFormat) string {
 	var builder strings.Builder
-	alertCount := len(data.Alerts)
-	loc, _ := time.LoadLocation("Asia/Shanghai")
+	now := time.Now()
+	firing, resolved := SplitByStatus(data)
 
-	if data.Status == "firing" {
+	if len(firing) > 0 {
 		builder.WriteString("### 🔥 Prometheus告警通知\n\n")
-		builder.WriteString(fmt.Sprintf(">请关注告警信息\n\n"))
+		builder.WriteString(">请关注告警信息\n\n")
 
-		for i, alert := range data.Alerts {
-			if alertCount > 1 && i > 0 {
+		for i, alert := range firing {
+			if i > 0 {
 				builder.WriteString("> ---\n")
 			}
 
@@ -62,43 +76,49 @@ func AlertFormatDingtalk(data template.Data) string {
 			builder.WriteString(fmt.Sprintf("**告警级别: <font color=\"%s\">%s</font>**\n\n", DingTalkMapSeverityColor(alert.Labels["severity"]), MapSeverity(alert.Labels["severity"])))
 			builder.WriteString(fmt.Sprintf("**监控实例:** %s\n\n", alert.Labels["instance"]))
 			builder.WriteString(fmt.Sprintf("**告警摘要:** %s\n\n", alert.Annotations["summary"]))
-			builder.WriteString(fmt.Sprintf("**触发时间:** %s\n\n", alert.StartsAt.In(loc).Format("2006-01-02 15:04:05")))
+			builder.WriteString(fmt.Sprintf("**触发时间:** %s\n\n", tf.Format(alert.StartsAt)))
+			builder.WriteString(fmt.Sprintf("**已持续:** %s\n\n", FormatDuration(AlertDuration(alert, data.Status, now))))
 
 			if desc, ok := alert.Annotations["description"]; ok && desc != "" {
 				builder.WriteString(fmt.Sprintf("**详细描述:** %s\n\n", desc))
 			}
 		}
-	} else if data.Status == "resolved" {
+	}
+
+	if len(resolved) > 0 {
 		builder.WriteString("### ✅ Prometheus告警恢复\n\n")
-		builder.WriteString(fmt.Sprintf("状态: **已恢复**\n\n"))
+		builder.WriteString("状态: **已恢复**\n\n")
 
-		for i, alert := range data.Alerts {
-			if alertCount > 1 && i > 0 {
+		for i, alert := range resolved {
+			if i > 0 {
 				builder.WriteString("> ---\n")
 			}
 
 			builder.WriteString(fmt.Sprintf("**告警名称**: %s\n", alert.Labels["alertname"]))
-			builder.WriteString(fmt.Sprintf("**恢复时间**: %s\n\n", alert.EndsAt.In(loc).Format("2006-01-02 15:04:05")))
+			builder.WriteString(fmt.Sprintf("**恢复时间**: %s\n", tf.Format(alert.EndsAt)))
+			builder.WriteString(fmt.Sprintf("**持续时间**: %s\n\n", FormatDuration(AlertDuration(alert, data.Status, now))))
 		}
 	}
 
 	return builder.String()
 }
 
-func AlertFormatWechat(data template.Data) string {
+// AlertFormatWechat 企业微信内置消息格式，时间按 tf 配置的时区和格式显示
+// 同一批中的告警按自身状态分为告警中和已恢复两部分
+func AlertFormatWechat(data template.Data, tf TimeFormat) string {
 	var msg string
-	alertCount := len(data.Alerts)
-	loc, _ := time.LoadLocation("Asia/Shanghai")
+	now := time.Now()
+	firing, resolved := SplitByStatus(data)
 
-	if data.Status == "firing" {
+	if len(firing) > 0 {
 		// 获取最高严重级别的告警来决定标题颜色
-		highestSeverity := getHighestSeverity(data.Alerts)
+		highestSeverity := HighestSeverity(firing)
 		msg += fmt.Sprintf("**🔥 <font size=18 color=\"%s\">Prometheus 告警通知</font>**\n", MapSeverityColor(highestSeverity))
 		msg += "请关注告警信息，相关人员请注意\n"
 		//msg += ">**状态: <font color=\"red\">告警中</font>**\n"
 
-		for i, alert := range data.Alerts {
-			if alertCount > 1 && i > 0 {
+		for i, alert := range firing {
+			if i > 0 {
 				msg += "\n"
 			}
 			msg += fmt.Sprintf(">**状态: <font color=\"%s\">告警中</font>**\n", MapSeverityColor(alert.Labels["severity"]))
@@ -107,26 +127,72 @@ func AlertFormatWechat(data template.Data) string {
 			msg += fmt.Sprintf(">**实例**: <font color=\"black\">%s</font>\n", alert.Labels["instance"])
 			msg += fmt.Sprintf(">**摘要**: <font color=\"black\">%s</font>\n", alert.Annotations["summary"])
 			msg += fmt.Sprintf(">**描述**: %s\n", alert.Annotations["description"])
-			msg += fmt.Sprintf(">**触发时间**: <font color=\"black\">%s</font>\n", alert.StartsAt.In(loc).Format("2006-01-02 15:04:05"))
+			msg += fmt.Sprintf(">**触发时间**: <font color=\"black\">%s</font>\n", tf.Format(alert.StartsAt))
+			msg += fmt.Sprintf(">**已持续**: <font color=\"black\">%s</font>\n", FormatDuration(AlertDuration(alert, data.Status, now)))
+		}
+	}
+
+	if len(resolved) > 0 {
+		if len(firing) > 0 {
+			msg += "\n"
 		}
-	} else if data.Status == "resolved" {
 		msg += "**♻ <font size=18 color=\"green\">Prometheus 告警恢复</font>**\n"
 		msg += ">**状态: <font color=\"green\">已恢复</font>**\n"
-		for i, alert := range data.Alerts {
-			if alertCount > 1 && i > 0 {
+		for i, alert := range resolved {
+			if i > 0 {
 				msg += ">---\n"
 			}
 			severity := alert.Labels["severity"]
 			color := MapSeverityColor(severity)
 
 			msg += fmt.Sprintf(">**告警名称: <font color=\"%s\">%s</font>**\n", color, alert.Labels["alertname"])
-			msg += fmt.Sprintf(">**恢复时间**: <font color=\"black\">%s</font>\n", alert.EndsAt.In(loc).Format("2006-01-02 15:04:05"))
+			msg += fmt.Sprintf(">**恢复时间**: <font color=\"black\">%s</font>\n", tf.Format(alert.EndsAt))
+			msg += fmt.Sprintf(">**持续时间**: <font color=\"black\">%s</font>\n", FormatDuration(AlertDuration(alert, data.Status, now)))
 		}
 	}
 
 	return msg
 }
 
+// AlertStatus 返回告警自身的状态，告警未携带状态时使用分组状态
+func AlertStatus(alert template.Alert, groupStatus string) string {
+	if alert.Status != "" {
+		return alert.Status
+	}
+	return groupStatus
+}
+
+// SplitByStatus 按告警自身状态拆分为告警中和已恢复两部分，保持原有顺序
+func SplitByStatus(data template.Data) (firing, resolved []template.Alert) {
+	for _, alert := range data.Alerts {
+		switch AlertStatus(alert, data.Status) {
+		case "firing":
+			firing = append(firing, alert)
+		case "resolved":
+			resolved = append(resolved, alert)
+		}
+	}
+	return firing, resolved
+}
+
+// GroupStatus 根据告警自身状态计算分组状态：存在告警中的告警时为 firing，全部恢复时为 resolved
+// 没有告警或状态无法判断时返回 fallback
+func GroupStatus(alerts []template.Alert, fallback string) string {
+	resolved := 0
+	for _, alert := range alerts {
+		switch AlertStatus(alert, fallback) {
+		case "firing":
+			return "firing"
+		case "resolved":
+			resolved++
+		}
+	}
+	if len(alerts) > 0 && resolved == len(alerts) {
+		return "resolved"
+	}
+	return fallback
+}
+
 // MapSeverity 映射告警等级为标准内部等级（如 P2/P3/P4）
 func MapSeverity(severity string) string {
 	switch severity {
@@ -143,9 +209,9 @@ func MapSeverity(severity string) string {
 	}
 }
 
-// getHighestSeverity 获取告警列表中的最高严重级别
+// HighestSeverity 获取告警列表中的最高严重级别
 // 优先级：emergency > critical > warning > info > 其他
-func getHighestSeverity(alerts []template.Alert) string {
+func HighestSeverity(alerts []template.Alert) string {
 	if len(alerts) == 0 {
 		return "info"
 	}
@@ -210,6 +276,52 @@ func DingTalkMapSeverityColor(severity string) string {
 	}
 }
 
+// SeverityHexColor 返回告警等级对应的十六进制颜色，按 MapSeverity 的 P0~P3 划分，用于 Slack、Teams 等支持任意颜色的渠道
+// 已恢复的告警统一使用绿色
+func SeverityHexColor(severity, status string) string {
+	if status == "resolved" {
+		return "#2EB67D"
+	}
+	switch MapSeverity(severity) {
+	case "P0":
+		return "#D00000"
+	case "P1":
+		return "#FF7F0E"
+	case "P2":
+		return "#FFD700"
+	case "P3":
+		return "#439FE0"
+	default:
+		return "#808080"
+	}
+}
+
+// AlertFingerprint 返回告警指纹，优先使用 Alertmanager 提供的指纹，缺少时（如内部生成的告警）按与 Alertmanager 相同的算法根据标签计算
+func AlertFingerprint(alert template.Alert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+	labels := make(model.LabelSet, len(alert.Labels))
+	for name, value := range alert.Labels {
+		labels[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return labels.Fingerprint().String()
+}
+
+// SilenceURL 返回在 Alertmanager 中按告警标签创建静默的链接，externalURL 为空时返回空字符串
+func SilenceURL(externalURL string, labels template.KV) string {
+	if externalURL == "" {
+		return ""
+	}
+
+	matchers := make([]string, 0, len(labels))
+	for _, p := range labels.SortedPairs() {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", p.Name, p.Value))
+	}
+	filter := "{" + strings.Join(matchers, ",") + "}"
+	return strings.TrimRight(externalURL, "/") + "/#/silences/new?filter=" + url.QueryEscape(filter)
+}
+
 func FilterValidAlerts(alerts []template.Alert) []template.Alert {
 	valid := make([]template.Alert, 0)
 	for _, alert := range alerts {
@@ -220,67 +332,58 @@ func FilterValidAlerts(alerts []template.Alert) []template.Alert {
 	return valid
 }
 
-// SplitWeChatAlerts 将告警按批次分组，确保每批消息不超过企业微信长度限制
-// 返回多个 template.Data，每个包含一部分告警
-func SplitWeChatAlerts(data template.Data) []template.Data {
-	const maxLength = 4000 // 企业微信限制4096字节，留一些安全边界
+// WeChatMaxLength 企业微信限制4096字节，留一些安全边界
+const WeChatMaxLength = 4000
 
+// SplitAlerts 将告警按批次分组，确保每批经 format 格式化后的消息不超过 maxLength 字节
+// maxLength 小于等于 0 时不拆分
+func SplitAlerts(data template.Data, maxLength int, format func(template.Data) string) []template.Data {
+	return SplitAlertsFunc(data, maxLength, func(batch template.Data) int {
+		return len(format(batch))
+	})
+}
+
+// SplitAlertsFunc 将告警按批次分组，确保每批的 measure 结果不超过 maxLength，
+// 用于按字符数等非字节长度限制消息的渠道；maxLength 小于等于 0 时不拆分
+func SplitAlertsFunc(data template.Data, maxLength int, measure func(template.Data) int) []template.Data {
 	var result []template.Data
 
-	// 如果没有告警，直接返回原数据
-	if len(data.Alerts) == 0 {
+	// 如果没有告警或不限制长度，直接返回原数据
+	if len(data.Alerts) == 0 || maxLength <= 0 {
 		return []template.Data{data}
 	}
 
 	// 先检查单个告警是否会超长
-	singleAlert := template.Data{
-		Status: data.Status,
-		Alerts: []template.Alert{data.Alerts[0]},
-	}
-	singleMsg := AlertFormatWechat(singleAlert)
+	singleAlert := batchOf(data, data.Alerts[0])
+	singleLength := measure(singleAlert)
 
 	// 如果单个告警就超长，那只能发送单个告警
-	if len(singleMsg) > maxLength {
-		log.Printf("[警告] 单个告警消息长度 %d 字节，超过微信限制，将尝试发送", len(singleMsg))
-		// 对于超长的单个告警，我们还是尝试发送，让微信返回错误
+	if singleLength > maxLength {
+		log.Printf("[警告] 单个告警消息长度 %d，超过限制 %d，将尝试发送", singleLength, maxLength)
+		// 对于超长的单个告警，我们还是尝试发送，让平台返回错误
 		for _, alert := range data.Alerts {
-			singleData := template.Data{
-				Status: data.Status,
-				Alerts: []template.Alert{alert},
-			}
-			result = append(result, singleData)
+			result = append(result, batchOf(data, alert))
 		}
 		return result
 	}
 
 	// 动态分组告警
-	currentBatch := template.Data{
-		Status: data.Status,
-		Alerts: []template.Alert{},
-	}
+	currentBatch := batchOf(data)
 
 	for _, alert := range data.Alerts {
 		// 尝试添加当前告警到批次中
-		testBatch := template.Data{
-			Status: data.Status,
-			Alerts: append(currentBatch.Alerts, alert),
-		}
-
-		testMsg := AlertFormatWechat(testBatch)
+		testBatch := batchOf(data, append(currentBatch.Alerts, alert)...)
 
 		// 如果添加后超长，先保存当前批次，然后开始新批次
-		if len(testMsg) > maxLength {
+		if measure(testBatch) > maxLength {
 			if len(currentBatch.Alerts) > 0 {
 				result = append(result, currentBatch)
 			}
 			// 开始新批次
-			currentBatch = template.Data{
-				Status: data.Status,
-				Alerts: []template.Alert{alert},
-			}
+			currentBatch = batchOf(data, alert)
 		} else {
 			// 可以添加到当前批次
-			currentBatch.Alerts = append(currentBatch.Alerts, alert)
+			currentBatch = testBatch
 		}
 	}
 
@@ -291,3 +394,36 @@ func SplitWeChatAlerts(data template.Data) []template.Data {
 
 	return result
 }
+
+// ChunkAlerts 按告警数量将告警分组，每组不超过 size 个告警，用于限制单条消息中附件、卡片数量的渠道
+// size 小于等于 0 时不拆分
+func ChunkAlerts(data template.Data, size int) []template.Data {
+	if size <= 0 || len(data.Alerts) <= size {
+		return []template.Data{data}
+	}
+
+	var result []template.Data
+	for start := 0; start < len(data.Alerts); start += size {
+		end := min(start+size, len(data.Alerts))
+		result = append(result, batchOf(data, data.Alerts[start:end]...))
+	}
+	return result
+}
+
+// SplitAlertsLimited 先按告警数量分组，每组不超过 maxAlerts 个告警，再按 measure 结果分批，
+// 用于同时限制单条消息中附件或卡片数量和消息长度的渠道，如 Slack、Discord
+func SplitAlertsLimited(data template.Data, maxAlerts, maxLength int, measure func(template.Data) int) []template.Data {
+	var result []template.Data
+	for _, chunk := range ChunkAlerts(data, maxAlerts) {
+		result = append(result, SplitAlertsFunc(chunk, maxLength, measure)...)
+	}
+	return result
+}
+
+// batchOf 复制 data 的公共字段，替换为指定的告警列表，并按告警自身状态重新计算分组状态
+func batchOf(data template.Data, alerts ...template.Alert) template.Data {
+	batch := data
+	batch.Alerts = append([]template.Alert{}, alerts...)
+	batch.Status = GroupStatus(batch.Alerts, data.Status)
+	return batch
+}
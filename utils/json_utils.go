@@ -2,21 +2,8 @@ package utils
 
 import (
 	"encoding/json"
-	"fmt"
 )
 
-// PrintJSON 将任意结构体以 JSON 格式美观地打印到控制台。
-// 参数：v 需要打印的结构体（interface{} 可以接收任何类型）
-// 当序列化失败时，会打印错误信息。
-func PrintJSON(v interface{}) {
-	jsonBytes, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		fmt.Printf("序列化失败: %v\n", err)
-		return
-	}
-	fmt.Println(string(jsonBytes))
-}
-
 // FormatJSON 将结构体序列化为格式化（缩进）的 JSON 字符串。
 func FormatJSON(v interface{}) (string, error) {
 	jsonBytes, err := json.MarshalIndent(v, "", "  ")
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// TemplateFuncs 自定义消息模板可用的辅助函数，时间按 tf 配置的时区和格式显示
+func TemplateFuncs(tf TimeFormat) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		// severity 映射为内部等级，如 critical -> P1
+		"mapSeverity": MapSeverity,
+		// severity 对应的企业微信字体颜色
+		"severityColor": MapSeverityColor,
+		// 按接收者配置的时区和格式格式化时间
+		"formatTime": tf.Format,
+		// 按指定格式格式化时间，时区使用接收者配置
+		"formatTimeLayout": func(layout string, t time.Time) string {
+			return TimeFormat{Location: tf.Location, Layout: layout}.Format(t)
+		},
+		// 告警已持续的时间，已恢复的告警为从触发到恢复的时间；告警缺少自身状态时可传入分组状态，如 {{ alertDuration . $.Status }}
+		"alertDuration": func(alert template.Alert, groupStatus ...string) string {
+			status := ""
+			if len(groupStatus) > 0 {
+				status = groupStatus[0]
+			}
+			return FormatDuration(AlertDuration(alert, status, time.Now()))
+		},
+		"formatDuration": FormatDuration,
+		// 将标签拼接为 k=v 形式，按名称排序
+		"joinLabels": func(sep string, kv template.KV) string {
+			pairs := make([]string, 0, len(kv))
+			for _, p := range kv.SortedPairs() {
+				pairs = append(pairs, p.Name+"="+p.Value)
+			}
+			return strings.Join(pairs, sep)
+		},
+		"join":    func(sep string, s []string) string { return strings.Join(s, sep) },
+		"toUpper": strings.ToUpper,
+		"toLower": strings.ToLower,
+		// 按字符截断，超出部分以 ... 结尾
+		"truncate": Truncate,
+		// 转义 Telegram MarkdownV2 和 HTML 中的特殊字符
+		"escapeMarkdownV2": EscapeMarkdownV2,
+		"escapeHTML":       html.EscapeString,
+		// 编码为 JSON，用于在 JSON 请求体模板中安全地嵌入字符串、标签等
+		"toJSON": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+}
+
+// markdownV2Replacer 转义 Telegram MarkdownV2 中需要转义的全部字符
+var markdownV2Replacer = strings.NewReplacer(
+	"\\", "\\\\", "_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+	"~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=",
+	"|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
+)
+
+// EscapeMarkdownV2 转义 Telegram MarkdownV2 中的特殊字符，用于标签值等任意文本
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+// Truncate 按字符数截断字符串，超出部分以 ... 结尾
+func Truncate(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// MessageTemplates 按名称加载的自定义消息模板集合
+type MessageTemplates map[string]*texttemplate.Template
+
+// LoadMessageTemplates 加载 名称 -> 模板文件 的自定义模板，相对路径相对于 baseDir
+func LoadMessageTemplates(files map[string]string, baseDir string) (MessageTemplates, error) {
+	templates := make(MessageTemplates, len(files))
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := files[name]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取消息模板 %s 失败: %w", name, err)
+		}
+
+		tmpl, err := texttemplate.New(name).Funcs(TemplateFuncs(DefaultTimeFormat())).Option("missingkey=zero").Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("解析消息模板 %s 失败: %w", name, err)
+		}
+		templates[name] = tmpl
+	}
+	return templates, nil
+}
+
+// MessageTemplate 接收者使用的消息模板，告警中和已恢复可以分别配置
+type MessageTemplate struct {
+	Firing   *texttemplate.Template
+	Resolved *texttemplate.Template
+}
+
+// NewMessageTemplate 复制已加载的模板，并将时间相关的辅助函数绑定到接收者的时区和格式
+// firing 或 resolved 为 nil 时对应状态使用内置格式
+func NewMessageTemplate(firing, resolved *texttemplate.Template, tf TimeFormat) (*MessageTemplate, error) {
+	bind := func(tmpl *texttemplate.Template) (*texttemplate.Template, error) {
+		if tmpl == nil {
+			return nil, nil
+		}
+		clone, err := tmpl.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("复制消息模板 %s 失败: %w", tmpl.Name(), err)
+		}
+		return clone.Funcs(TemplateFuncs(tf)), nil
+	}
+
+	m := &MessageTemplate{}
+	var err error
+	if m.Firing, err = bind(firing); err != nil {
+		return nil, err
+	}
+	if m.Resolved, err = bind(resolved); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Render 按告警状态渲染模板，未配置对应状态的模板时返回 false，调用方使用内置格式
+// 同时包含告警中和已恢复告警的分组，两种模板都已配置时分别渲染告警中和已恢复的部分后拼接；
+// 只配置了 firing 模板时以完整数据渲染 firing 模板，模板应通过 .Alerts.Resolved 展示已恢复的告警
+func (m *MessageTemplate) Render(data template.Data) (string, bool, error) {
+	if m == nil {
+		return "", false, nil
+	}
+
+	firing, resolved := SplitByStatus(data)
+	if len(firing) > 0 && len(resolved) > 0 && m.Firing != nil && m.Resolved != nil {
+		firingText, err := execute(m.Firing, batchOf(data, firing...))
+		if err != nil {
+			return "", true, err
+		}
+		resolvedText, err := execute(m.Resolved, batchOf(data, resolved...))
+		if err != nil {
+			return "", true, err
+		}
+		return strings.TrimRight(firingText, "\n") + "\n\n" + resolvedText, true, nil
+	}
+
+	tmpl := m.Firing
+	if data.Status == "resolved" {
+		tmpl = m.Resolved
+	}
+	if tmpl == nil {
+		return "", false, nil
+	}
+
+	text, err := execute(tmpl, data)
+	if err != nil {
+		return "", true, err
+	}
+	return text, true, nil
+}
+
+// execute 渲染一个模板
+func execute(tmpl *texttemplate.Template, data template.Data) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染消息模板 %s 失败: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// Formatter 返回使用自定义模板的格式化函数，未配置对应状态的模板时使用 builtin，渲染失败时返回错误
+func (m *MessageTemplate) Formatter(builtin func(template.Data) string) func(template.Data) (string, error) {
+	return func(data template.Data) (string, error) {
+		text, ok, err := m.Render(data)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return builtin(data), nil
+		}
+		return text, nil
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func mixedData() template.Data {
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	return template.Data{
+		Status:       "firing",
+		CommonLabels: template.KV{"alertname": "HighCPU"},
+		Alerts: template.Alerts{
+			{Status: "firing", Labels: template.KV{"alertname": "HighCPU", "instance": "node-1", "severity": "critical"}, StartsAt: start},
+			{Status: "resolved", Labels: template.KV{"alertname": "HighCPU", "instance": "node-2", "severity": "critical"}, StartsAt: start, EndsAt: start.Add(time.Hour)},
+		},
+	}
+}
+
+func loadTemplates(t *testing.T, files map[string]string) MessageTemplates {
+	t.Helper()
+	templates, err := LoadMessageTemplates(files, "../templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return templates
+}
+
+func TestRenderMixedGroupWithBothTemplates(t *testing.T) {
+	templates := loadTemplates(t, map[string]string{
+		"firing":   "wechat-firing.tmpl",
+		"resolved": "wechat-resolved.tmpl",
+	})
+	tmpl, err := NewMessageTemplate(templates["firing"], templates["resolved"], DefaultTimeFormat())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, ok, err := tmpl.Render(mixedData())
+	if err != nil || !ok {
+		t.Fatalf("Render = %v, %v", ok, err)
+	}
+	for _, want := range []string{"node-1", "node-2", "[已恢复]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("rendered text missing %q:\n%s", want, text)
+		}
+	}
+	// 告警中的部分只包含告警中的告警
+	firingPart := text[:strings.Index(text, "[已恢复]")]
+	if strings.Contains(firingPart, "node-2") {
+		t.Errorf("resolved alert rendered in firing section:\n%s", text)
+	}
+}
+
+func TestRenderMixedGroupWithFiringTemplateOnly(t *testing.T) {
+	templates := loadTemplates(t, map[string]string{"firing": "wechat-firing.tmpl"})
+	tmpl, err := NewMessageTemplate(templates["firing"], nil, DefaultTimeFormat())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, _, err := tmpl.Render(mixedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "node-1") || !strings.Contains(text, "node-2") {
+		t.Errorf("mixed group should render both alerts:\n%s", text)
+	}
+}
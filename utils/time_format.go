@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// 内置时区数据库，精简镜像中没有 /usr/share/zoneinfo 时也能加载时区
+	_ "time/tzdata"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// DefaultTimezone 告警消息中时间的默认时区
+const DefaultTimezone = "Asia/Shanghai"
+
+// DefaultTimeLayout 告警消息中时间的默认格式
+const DefaultTimeLayout = "2006-01-02 15:04:05"
+
+// TimeFormat 告警消息中时间的显示时区和格式
+type TimeFormat struct {
+	Location *time.Location
+	Layout   string
+}
+
+// DefaultTimeFormat 未配置 timezone 和 time_format 时使用的默认值
+func DefaultTimeFormat() TimeFormat {
+	tf, err := NewTimeFormat(DefaultTimezone, DefaultTimeLayout)
+	if err != nil {
+		// 时区数据已内置，正常不会走到这里
+		return TimeFormat{Location: time.FixedZone("CST", 8*60*60), Layout: DefaultTimeLayout}
+	}
+	return tf
+}
+
+// NewTimeFormat 校验并创建时间格式，timezone 为 IANA 时区名称（如 Asia/Shanghai、UTC），layout 为 Go 时间格式
+func NewTimeFormat(timezone, layout string) (TimeFormat, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return TimeFormat{}, fmt.Errorf("无效的时区 %s: %w", timezone, err)
+	}
+
+	// 格式中不包含任何时间占位符时，格式化结果与格式本身相同
+	sample := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	if strings.TrimSpace(layout) == "" || sample.Format(layout) == layout {
+		return TimeFormat{}, fmt.Errorf("无效的时间格式 %q，应使用 Go 时间格式，如 2006-01-02 15:04:05", layout)
+	}
+
+	return TimeFormat{Location: loc, Layout: layout}, nil
+}
+
+// Format 按配置的时区和格式格式化时间
+func (f TimeFormat) Format(t time.Time) string {
+	if f.Location == nil {
+		return t.Format(f.Layout)
+	}
+	return t.In(f.Location).Format(f.Layout)
+}
+
+// AlertDuration 告警已持续的时间，已恢复的告警返回从触发到恢复的时间；告警状态按 AlertStatus 计算，缺少自身状态时使用分组状态
+func AlertDuration(alert template.Alert, groupStatus string, now time.Time) time.Duration {
+	if alert.StartsAt.IsZero() {
+		return 0
+	}
+	end := now
+	if AlertStatus(alert, groupStatus) == "resolved" && !alert.EndsAt.IsZero() {
+		end = alert.EndsAt
+	}
+	if end.Before(alert.StartsAt) {
+		return 0
+	}
+	return end.Sub(alert.StartsAt)
+}
+
+// FormatDuration 将时长格式化为 1天2小时3分钟 的形式，不足1分钟时显示秒数
+func FormatDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	if d < time.Minute {
+		return fmt.Sprintf("%d秒", int(d.Seconds()))
+	}
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+
+	var builder strings.Builder
+	if days > 0 {
+		builder.WriteString(fmt.Sprintf("%d天", days))
+	}
+	if hours > 0 {
+		builder.WriteString(fmt.Sprintf("%d小时", hours))
+	}
+	if minutes > 0 {
+		builder.WriteString(fmt.Sprintf("%d分钟", minutes))
+	}
+	return builder.String()
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestNewTimeFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		layout   string
+		wantErr  bool
+		want     string
+	}{
+		{"default", DefaultTimezone, DefaultTimeLayout, false, "2026-10-17 18:30:00"},
+		{"utc rfc3339", "UTC", time.RFC3339, false, "2026-10-17T10:30:00Z"},
+		{"custom layout", "America/New_York", "01/02 15:04 MST", false, "10/17 06:30 EDT"},
+		{"invalid zone", "Mars/Olympus", DefaultTimeLayout, true, ""},
+		{"no placeholders", "UTC", "YYYY-MM-DD hh:mm:ss", true, ""},
+		{"empty layout", "UTC", "  ", true, ""},
+	}
+	at := time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := NewTimeFormat(tt.timezone, tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewTimeFormat(%q, %q) err = %v, wantErr %v", tt.timezone, tt.layout, err, tt.wantErr)
+			}
+			if err == nil && tf.Format(at) != tt.want {
+				t.Errorf("Format = %q, want %q", tf.Format(at), tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0秒"},
+		{45*time.Second + 900*time.Millisecond, "45秒"},
+		{time.Minute, "1分钟"},
+		{2*time.Hour + 5*time.Minute + 30*time.Second, "2小时5分钟"},
+		{3 * time.Hour, "3小时"},
+		{23*time.Hour + 59*time.Minute, "23小时59分钟"},
+		{24 * time.Hour, "1天"},
+		{26*time.Hour + 3*time.Minute, "1天2小时3分钟"},
+		{50*time.Hour + 10*time.Second, "2天2小时"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestAlertDuration(t *testing.T) {
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	now := start.Add(3 * time.Hour)
+	tests := []struct {
+		name        string
+		alert       template.Alert
+		groupStatus string
+		want        time.Duration
+	}{
+		{"firing", template.Alert{Status: "firing", StartsAt: start}, "firing", 3 * time.Hour},
+		{"resolved", template.Alert{Status: "resolved", StartsAt: start, EndsAt: start.Add(time.Hour)}, "firing", time.Hour},
+		// 告警缺少自身状态时按分组状态判断
+		{"resolved group", template.Alert{StartsAt: start, EndsAt: start.Add(time.Hour)}, "resolved", time.Hour},
+		{"firing group", template.Alert{StartsAt: start, EndsAt: start.Add(time.Hour)}, "firing", 3 * time.Hour},
+		// 告警自身状态优先于分组状态
+		{"alert status wins", template.Alert{Status: "firing", StartsAt: start, EndsAt: start.Add(time.Hour)}, "resolved", 3 * time.Hour},
+		{"no start", template.Alert{Status: "firing"}, "firing", 0},
+		{"start in future", template.Alert{Status: "firing", StartsAt: now.Add(time.Minute)}, "firing", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AlertDuration(tt.alert, tt.groupStatus, now); got != tt.want {
+				t.Errorf("AlertDuration = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}